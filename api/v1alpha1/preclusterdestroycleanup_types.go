@@ -17,7 +17,10 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -27,31 +30,168 @@ const (
 	ActionUnknown     = ""
 	ActionDelete      = "delete"
 	ActionScaleToZero = "scaleToZero"
+	ActionSuspend     = "suspend"
+	ActionPatch       = "patch"
 )
 
+const (
+	PatchTypeMerge = "merge"
+	PatchTypeJSON  = "json"
+)
+
+const (
+	ItemStatePending   = "Pending"
+	ItemStateWaiting   = "Waiting"
+	ItemStateCompleted = "Completed"
+	ItemStateFailed    = "Failed"
+)
+
+const (
+	ResultDeleted   = "Deleted"
+	ResultScaled    = "Scaled"
+	ResultForced    = "Forced"
+	ResultSkipped   = "Skipped"
+	ResultFailed    = "Failed"
+	ResultRestored  = "Restored"
+	ResultSuspended = "Suspended"
+	ResultPatched   = "Patched"
+)
+
+// PreviousReplicasAnnotation records the replica count of a workload before it was scaled, so that it can be restored.
+const PreviousReplicasAnnotation = "cleanup.quartz.metrostar.com/previous-replicas"
+
+// ChunksAnnotation records on the first ConfigMap of data stored in chunks how many chunks the data was split into.
+const ChunksAnnotation = "cleanup.quartz.metrostar.com/chunks"
+
+// ProtectAnnotation marks an object that must never be touched by a cleanup when set to "true".
+const ProtectAnnotation = "cleanup.quartz.metrostar.com/protect"
+
+// RunNonceAnnotation requests a new run of a completed PreClusterDestroyCleanup when its value is changed.
+const RunNonceAnnotation = "cleanup.quartz.metrostar.com/run-nonce"
+
+// PreClusterDestroyCleanupExclude selects objects that an item must skip.
+type PreClusterDestroyCleanupExclude struct {
+	Namespaces    []string              `json:"namespaces,omitempty"`    // Optional: Skip objects in these namespaces
+	Names         []string              `json:"names,omitempty"`         // Optional: Skip objects with these names
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"` // Optional: Skip objects with matching labels
+}
+
+// PreClusterDestroyCleanupPatch is a patch applied to every object matched by an item.
+type PreClusterDestroyCleanupPatch struct {
+	// +kubebuilder:validation:Enum=merge;json
+	Type string               `json:"type,omitempty"` // Optional: Type of the patch, "merge" for a JSON merge patch (default) or "json" for a JSON patch
+	Data apiextensionsv1.JSON `json:"data"`           // Data is the patch document, e.g., {"spec":{"type":"ClusterIP"}}
+}
+
+// +kubebuilder:validation:XValidation:rule="!has(self.action) || self.action != 'patch' || has(self.patch)",message="patch must be specified for the patch action"
+// +kubebuilder:validation:XValidation:rule="!has(self.name) || (!has(self.labelSelector) && !has(self.fieldSelector))",message="labelSelector and fieldSelector cannot be combined with name"
 type PreClusterDestroyCleanupItem struct {
-	Kind      string `json:"kind,omitempty"`      // Kind is the name of the kind.
+	Kind      string `json:"kind,omitempty"`      // Kind is the name of the kind, it can be left empty to delete by category
 	Namespace string `json:"namespace,omitempty"` // Optional: Namespace where the resource is located
 	Name      string `json:"name,omitempty"`      // Optional: Name of the resource
-	Category  string `json:"category,omitempty"`  // Category is the category of the resource, e.g., "networking", "storage", etc.
+	Category  string `json:"category,omitempty"`  // Optional: Category deletes every kind in the category, e.g., "managed", like "kubectl get managed", when kind is empty or CustomResourceDefinition
+
+	Namespaces        []string              `json:"namespaces,omitempty"`        // Optional: Additional namespaces where the resource is located
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"` // Optional: Also target namespaces with matching labels
+
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"` // Optional: Only target resources with matching labels
+	FieldSelector string                `json:"fieldSelector,omitempty"` // Optional: Only target resources with matching fields, e.g., "spec.type=LoadBalancer"
+
+	// +kubebuilder:validation:Minimum=1
+	PageSize int64 `json:"pageSize,omitempty"` // Optional: How many objects to list per request, overrides the page size of the operator
 
-	// +kubebuilder:validation:Enum=delete;scaleToZero
-	Action string `json:"action,omitempty"` // Action is the action to be taken on the resource, e.g., "delete", "scaleToZero", etc.
+	Exclude *PreClusterDestroyCleanupExclude `json:"exclude,omitempty"` // Optional: Skip matching resources that should be kept
+
+	Phase string `json:"phase,omitempty"` // Optional: Phase groups items; phases run in order of first appearance, each after the previous one has converged
+
+	WaitForDeletion bool             `json:"waitForDeletion,omitempty"` // Optional: Wait until deleted objects are gone from the cluster before the item is completed
+	Timeout         *metav1.Duration `json:"timeout,omitempty"`         // Optional: How long to wait for the item to converge before it is failed, e.g., "10m"
+	ForceAfter      *metav1.Duration `json:"forceAfter,omitempty"`      // Optional: Remove the finalizers of deleted objects that are still terminating after this duration
+
+	// +kubebuilder:validation:Enum=delete;scaleToZero;suspend;patch
+	Action string                         `json:"action,omitempty"` // Action is the action to be taken on the resource, e.g., "delete", "scaleToZero", "suspend", "patch", etc.
+	Patch  *PreClusterDestroyCleanupPatch `json:"patch,omitempty"`  // Optional: Patch applied to each matched resource by the patch action
 }
 
 // PreClusterDestroyCleanupSpec defines the desired state of PreClusterDestroyCleanup.
 type PreClusterDestroyCleanupSpec struct {
-	DryRun    bool                           `json:"dryRun,omitempty"` // DryRun indicates whether the cleanup should be performed or just logged
-	Resources []PreClusterDestroyCleanupItem `json:"resources,omitempty"`
+	DryRun           bool                           `json:"dryRun,omitempty"` // DryRun sends every request with dryRun=All, so the API server validates it without persisting any change
+	Revert           bool                           `json:"revert,omitempty"` // Optional: Scale the workloads recorded as scaled in status back to their previous replica count instead of cleaning up
+	Resources        []PreClusterDestroyCleanupItem `json:"resources,omitempty"`
+	ApprovedPlanHash string                         `json:"approvedPlanHash,omitempty"` // Optional: Hash of the reviewed plan, from status.planHash; the cleanup only runs while the objects it would act on still match it
+
+	// +kubebuilder:validation:Minimum=0
+	MaxRetries *int32           `json:"maxRetries,omitempty"` // Optional: How many times a failed item is retried before the cleanup fails, defaults to 3; 0 fails the cleanup on the first error
+	Backoff    *metav1.Duration `json:"backoff,omitempty"`    // Optional: How long to wait before the first retry of a failed item, doubled for each further retry, defaults to "10s"
+}
+
+// PreClusterDestroyCleanupObjectStatus records the outcome of an action on a single object.
+type PreClusterDestroyCleanupObjectStatus struct {
+	APIVersion string    `json:"apiVersion,omitempty"` // APIVersion of the object
+	Kind       string    `json:"kind,omitempty"`       // Kind of the object
+	Namespace  string    `json:"namespace,omitempty"`  // Namespace of the object, empty for cluster scoped objects
+	Name       string    `json:"name"`                 // Name of the object
+	UID        types.UID `json:"uid,omitempty"`        // UID of the object at the time the action was taken
+
+	// +kubebuilder:validation:Enum=Deleted;Scaled;Forced;Skipped;Failed;Restored;Suspended;Patched
+	Result  string `json:"result"`            // Result is the outcome of the action, e.g., "Deleted", "Scaled", "Forced", "Skipped", "Failed", "Restored", "Suspended", "Patched"
+	Message string `json:"message,omitempty"` // Message holds the error or reason for the result, if any
+
+	RemovedFinalizers []string `json:"removedFinalizers,omitempty"` // RemovedFinalizers lists the finalizers that were removed to force the deletion of the object
+}
+
+// PreClusterDestroyCleanupItemStatus records the execution results for an entry in spec.resources.
+type PreClusterDestroyCleanupItemStatus struct {
+	Index   int    `json:"index"`             // Index of the item in spec.resources
+	Group   string `json:"group,omitempty"`   // Group of the resolved kind
+	Version string `json:"version,omitempty"` // Version of the resolved kind
+	Kind    string `json:"kind,omitempty"`    // Kind resolved from the item
+	Action  string `json:"action,omitempty"`  // Action taken on the item
+	Phase   string `json:"phase,omitempty"`   // Phase the item belongs to
+	Message string `json:"message,omitempty"` // Message holds the error that prevented the item from being processed, if any
+
+	Namespaces []string `json:"namespaces,omitempty"` // Namespaces matched by the item, empty when the item is not scoped to namespaces
+
+	DiscoveredKinds []metav1.GroupVersionKind `json:"discoveredKinds,omitempty"` // DiscoveredKinds lists the kinds found by the category of the item, with the version used to act on them
+
+	// +kubebuilder:validation:Enum=Pending;Waiting;Completed;Failed
+	State     string       `json:"state,omitempty"`     // State of the item, e.g., "Pending", "Waiting", "Completed", "Failed"
+	StartTime *metav1.Time `json:"startTime,omitempty"` // StartTime is when the action of the item was performed
+
+	Attempts      int32        `json:"attempts,omitempty"`      // Attempts is how many times the action of the item was performed
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"` // NextRetryTime is when a failed item is retried, if any
+
+	Objects []PreClusterDestroyCleanupObjectStatus `json:"objects,omitempty"` // Objects touched by the item
+
+	ApprovedUIDs []types.UID `json:"approvedUIDs,omitempty"` // ApprovedUIDs lists the objects of the approved plan of the item; while spec.approvedPlanHash is set, no other object is acted on
+
+	Results          map[string]int `json:"results,omitempty"`          // Results counts the objects touched by the item by result
+	ObjectsTruncated bool           `json:"objectsTruncated,omitempty"` // ObjectsTruncated is true when the item touched too many objects for the status; objects then only lists failures and skips, and the full objects and approvedUIDs are stored in the ConfigMaps of status.objectsStored
 }
 
 // PreClusterDestroyCleanupStatus defines the observed state of PreClusterDestroyCleanup.
 type PreClusterDestroyCleanupStatus struct {
-	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+	Conditions []metav1.Condition                   `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+	Items      []PreClusterDestroyCleanupItemStatus `json:"items,omitempty"` // Items mirrors spec.resources with the results of each item
+	Phase      string                               `json:"phase,omitempty"` // Phase currently being processed
+
+	PlanRef  *corev1.LocalObjectReference `json:"planRef,omitempty"`  // PlanRef references the ConfigMap holding the plan of the last dry run
+	PlanHash string                       `json:"planHash,omitempty"` // PlanHash is the hash of the last plan, to be copied to spec.approvedPlanHash once reviewed
+
+	ObjectsStored []string `json:"objectsStored,omitempty"` // ObjectsStored names the ConfigMaps storing the full objects of the items whose objects are truncated, as gzipped JSON
+
+	PlanTruncated bool `json:"planTruncated,omitempty"` // PlanTruncated is true when the last plan was too large for its ConfigMap, which then only holds the number of objects of each item and the names of the ConfigMaps storing the full plan
+
+	RunGeneration      int64  `json:"runGeneration,omitempty"`      // RunGeneration is the generation of the spec the run recorded in items started at
+	ObservedGeneration int64  `json:"observedGeneration,omitempty"` // ObservedGeneration is the generation of the spec whose run last completed
+	ObservedRunNonce   string `json:"observedRunNonce,omitempty"`   // ObservedRunNonce is the run nonce annotation of the run that last completed
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // PreClusterDestroyCleanup is the Schema for the preclusterdestroycleanups API.
 type PreClusterDestroyCleanup struct {
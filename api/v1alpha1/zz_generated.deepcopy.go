@@ -21,8 +21,10 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -52,9 +54,74 @@ func (in *PreClusterDestroyCleanup) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreClusterDestroyCleanupExclude) DeepCopyInto(out *PreClusterDestroyCleanupExclude) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreClusterDestroyCleanupExclude.
+func (in *PreClusterDestroyCleanupExclude) DeepCopy() *PreClusterDestroyCleanupExclude {
+	if in == nil {
+		return nil
+	}
+	out := new(PreClusterDestroyCleanupExclude)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PreClusterDestroyCleanupItem) DeepCopyInto(out *PreClusterDestroyCleanupItem) {
 	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = new(PreClusterDestroyCleanupExclude)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ForceAfter != nil {
+		in, out := &in.ForceAfter, &out.ForceAfter
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Patch != nil {
+		in, out := &in.Patch, &out.Patch
+		*out = new(PreClusterDestroyCleanupPatch)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreClusterDestroyCleanupItem.
@@ -67,6 +134,58 @@ func (in *PreClusterDestroyCleanupItem) DeepCopy() *PreClusterDestroyCleanupItem
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreClusterDestroyCleanupItemStatus) DeepCopyInto(out *PreClusterDestroyCleanupItemStatus) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DiscoveredKinds != nil {
+		in, out := &in.DiscoveredKinds, &out.DiscoveredKinds
+		*out = make([]v1.GroupVersionKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextRetryTime != nil {
+		in, out := &in.NextRetryTime, &out.NextRetryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Objects != nil {
+		in, out := &in.Objects, &out.Objects
+		*out = make([]PreClusterDestroyCleanupObjectStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ApprovedUIDs != nil {
+		in, out := &in.ApprovedUIDs, &out.ApprovedUIDs
+		*out = make([]types.UID, len(*in))
+		copy(*out, *in)
+	}
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreClusterDestroyCleanupItemStatus.
+func (in *PreClusterDestroyCleanupItemStatus) DeepCopy() *PreClusterDestroyCleanupItemStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PreClusterDestroyCleanupItemStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PreClusterDestroyCleanupList) DeepCopyInto(out *PreClusterDestroyCleanupList) {
 	*out = *in
@@ -99,13 +218,61 @@ func (in *PreClusterDestroyCleanupList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreClusterDestroyCleanupObjectStatus) DeepCopyInto(out *PreClusterDestroyCleanupObjectStatus) {
+	*out = *in
+	if in.RemovedFinalizers != nil {
+		in, out := &in.RemovedFinalizers, &out.RemovedFinalizers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreClusterDestroyCleanupObjectStatus.
+func (in *PreClusterDestroyCleanupObjectStatus) DeepCopy() *PreClusterDestroyCleanupObjectStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PreClusterDestroyCleanupObjectStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreClusterDestroyCleanupPatch) DeepCopyInto(out *PreClusterDestroyCleanupPatch) {
+	*out = *in
+	in.Data.DeepCopyInto(&out.Data)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreClusterDestroyCleanupPatch.
+func (in *PreClusterDestroyCleanupPatch) DeepCopy() *PreClusterDestroyCleanupPatch {
+	if in == nil {
+		return nil
+	}
+	out := new(PreClusterDestroyCleanupPatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PreClusterDestroyCleanupSpec) DeepCopyInto(out *PreClusterDestroyCleanupSpec) {
 	*out = *in
 	if in.Resources != nil {
 		in, out := &in.Resources, &out.Resources
 		*out = make([]PreClusterDestroyCleanupItem, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Backoff != nil {
+		in, out := &in.Backoff, &out.Backoff
+		*out = new(v1.Duration)
+		**out = **in
 	}
 }
 
@@ -129,6 +296,23 @@ func (in *PreClusterDestroyCleanupStatus) DeepCopyInto(out *PreClusterDestroyCle
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PreClusterDestroyCleanupItemStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PlanRef != nil {
+		in, out := &in.PlanRef, &out.PlanRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.ObjectsStored != nil {
+		in, out := &in.ObjectsStored, &out.ObjectsStored
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreClusterDestroyCleanupStatus.
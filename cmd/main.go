@@ -17,16 +17,26 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -40,6 +50,7 @@ import (
 
 	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
 	"github.com/MetroStar/quartz-operator/internal/controller"
+	"github.com/MetroStar/quartz-operator/internal/services"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -64,6 +75,13 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var denyNamespaces, denyKinds string
+	var suspendStrategiesPath string
+	var targetEvents bool
+	var otlpEndpoint string
+	var otlpInsecure bool
+	var traceSamplingRatio float64
+	var listPageSize int64
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -82,6 +100,22 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&denyNamespaces, "deny-namespaces", "kube-system,kube-public,kube-node-lease",
+		"Comma separated list of namespaces whose objects are never touched by a cleanup.")
+	flag.StringVar(&denyKinds, "deny-kinds", "",
+		"Comma separated list of kinds, as Kind or Kind.group, that are never touched by a cleanup.")
+	flag.StringVar(&suspendStrategiesPath, "suspend-strategies", "",
+		"Path to a YAML file with additional suspend strategies, each a kind and the JSON merge patch that suspends it.")
+	flag.BoolVar(&targetEvents, "target-events", false,
+		"If set, events are also recorded on each object deleted, scaled, suspended or patched by a cleanup.")
+	flag.Int64Var(&listPageSize, "list-page-size", services.DefaultPageSize,
+		"The number of objects listed per request, unless set by the item with pageSize.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"The host:port of an OTLP gRPC collector to export traces to. Tracing is disabled if empty.")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false,
+		"If set, traces are exported to the OTLP collector without TLS.")
+	flag.Float64Var(&traceSamplingRatio, "trace-sampling-ratio", 1,
+		"The ratio of reconciles that are traced, between 0 and 1, unless the parent span was sampled.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -179,7 +213,22 @@ func main() {
 		})
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	restConfig := ctrl.GetConfigOrDie()
+	var shutdownTracing func(context.Context) error
+	if otlpEndpoint != "" {
+		var err error
+		shutdownTracing, err = setupTracing(context.Background(), otlpEndpoint, otlpInsecure, traceSamplingRatio)
+		if err != nil {
+			setupLog.Error(err, "unable to set up tracing")
+			os.Exit(1)
+		}
+		// propagate the trace context of each reconcile into the requests to the API server
+		restConfig.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return otelhttp.NewTransport(rt)
+		})
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
@@ -203,10 +252,30 @@ func main() {
 		os.Exit(1)
 	}
 
+	var suspendStrategies []services.SuspendStrategy
+	if suspendStrategiesPath != "" {
+		suspendStrategies, err = services.LoadSuspendStrategies(suspendStrategiesPath)
+		if err != nil {
+			setupLog.Error(err, "unable to load suspend strategies")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&controller.PreClusterDestroyCleanupReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Config: mgr.GetConfig(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Config:   mgr.GetConfig(),
+		Recorder: mgr.GetEventRecorderFor("preclusterdestroycleanup-controller"),
+		Options: services.Options{
+			DenyList: services.DenyList{
+				Namespaces: splitList(denyNamespaces),
+				Kinds:      splitList(denyKinds),
+			},
+			SuspendStrategies: suspendStrategies,
+			TargetEvents:      targetEvents,
+			PageSize:          listPageSize,
+			Reader:            mgr.GetAPIReader(),
+		},
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PreClusterDestroyCleanup")
 		os.Exit(1)
@@ -239,8 +308,58 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	if shutdownTracing != nil {
+		if err := shutdownTracing(context.Background()); err != nil {
+			setupLog.Error(err, "problem flushing traces")
+		}
+	}
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// setupTracing exports the spans of the operator to an OTLP gRPC collector, sampling traces with ratio,
+// and propagates the trace context with W3C trace context headers.
+// It returns a function that flushes the remaining spans on shutdown.
+func setupTracing(
+	ctx context.Context, endpoint string, insecure bool, ratio float64,
+) (func(context.Context) error, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", "quartz-operator")),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// splitList splits a comma separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	list := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -19,92 +19,212 @@ package controller
 import (
 	"context"
 	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
 	"github.com/MetroStar/quartz-operator/internal/services"
 )
 
 const (
-	ConditionComplete           = "Complete"
-	ConditionInitialized        = "Initialized"
+	ConditionReady              = "Ready"
+	ConditionProgressing        = "Progressing"
+	ConditionDegraded           = "Degraded"
+	ConditionFailed             = "Failed"
 	ReasonCompletedSuccessfully = "CompletedSuccessfully"
 	ReasonCompletedWithErrors   = "CompletedWithErrors"
+	ReasonItemsFailed           = "ItemsFailed"
 	ReasonNoResources           = "NoResources"
+	ReasonPlanDrifted           = "PlanDrifted"
 	ReasonReconciling           = "Reconciling"
+	ReasonRetryingItems         = "RetryingItems"
+	ReasonReverted              = "Reverted"
+	ReasonRevertedWithErrors    = "RevertedWithErrors"
+	ReasonWaitingForPhase       = "WaitingForPhase"
 )
 
+// conditionComplete is the condition replaced by Ready, Progressing and Degraded, removed from objects that still have it.
+const conditionComplete = "Complete"
+
+// WaitRequeueInterval is how long to wait before checking again whether a phase has converged.
+const WaitRequeueInterval = 5 * time.Second
+
+// MinRetryRequeueInterval is the shortest time to wait for the retry of a failed item.
+const MinRetryRequeueInterval = time.Second
+
 // PreClusterDestroyCleanupReconciler reconciles a PreClusterDestroyCleanup object
 type PreClusterDestroyCleanupReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Config *rest.Config
+
+	// Recorder records events about the progress of each cleanup. No events are recorded if it is nil.
+	Recorder record.EventRecorder
+
+	// Options holds the controller level settings of the cleanup, like the deny list and suspend strategies.
+	Options services.Options
 }
 
 // +kubebuilder:rbac:groups=cleanup.quartz.metrostar.com,resources=preclusterdestroycleanups,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=cleanup.quartz.metrostar.com,resources=preclusterdestroycleanups/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=cleanup.quartz.metrostar.com,resources=preclusterdestroycleanups/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=update;patch
-// +kubebuilder:rbac:groups=*,resources=*,verbs=delete;list;get;watch
+// +kubebuilder:rbac:groups=*,resources=*/scale,verbs=get;update;patch
+// +kubebuilder:rbac:groups=*,resources=*,verbs=delete;list;get;watch;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.20.2/pkg/reconcile
+//
+// Each reconcile is recorded in a span, parent of the spans of the items and objects it acts on.
 func (r *PreClusterDestroyCleanupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := services.StartSpan(ctx, "Reconcile", services.AttributeNamespace.String(req.Namespace), services.AttributeName.String(req.Name))
+	result, err := r.reconcile(ctx, req)
+	services.EndSpan(span, err)
+	return result, err
+}
+
+// reconcile runs the cleanup of a PreClusterDestroyCleanup, or reverts it, and records its progress in status.
+func (r *PreClusterDestroyCleanupReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("Reconciling PreClusterDestroyCleanup", "name", req.Name, "namespace", req.Namespace)
 
 	obj := &cleanupv1alpha1.PreClusterDestroyCleanup{}
 	if err := r.Client.Get(ctx, req.NamespacedName, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			services.ForgetMetrics(req.NamespacedName)
+		}
 		logger.Error(err, "unable to fetch PreClusterDestroyCleanup")
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
 	update := services.NewUpdateService(r.Client)
+	events := services.NewEventService(r.Recorder, r.Options.TargetEvents)
 
+	// status changes are batched and written with a single patch against base at the end of the reconcile
+	base := obj.DeepCopy()
 	if len(obj.Status.Conditions) == 0 {
 		// Initialize conditions if not set
-		if err := update.UpdateCondition(ctx, obj, ConditionInitialized, ReasonReconciling, "Reconciliation started"); err != nil {
-			logger.Error(err, "failed to initialize PreClusterDestroyCleanup status conditions")
-			return ctrl.Result{}, err
-		}
-		logger.Info("Initialized status conditions for PreClusterDestroyCleanup", "name", req.Name, "namespace", req.Namespace)
+		setConditions(update, obj, false, true, false, ReasonReconciling, "Reconciliation started")
+	}
 
-		if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
-			logger.Error(err, "failed to re-fetch PreClusterDestroyCleanup after initializing status conditions")
-			return ctrl.Result{}, client.IgnoreNotFound(err)
-		}
+	if runCompleted(obj) {
+		logger.Info("Run already completed for this generation, skipping", "generation", obj.Generation)
+		return ctrl.Result{}, nil
+	}
+
+	// items with too many objects for the status keep their full objects in ConfigMaps, see patchStatus
+	if err := services.NewStoreService(ctx, r.Client, r.Options).LoadObjects(ctx, obj); err != nil {
+		logger.Error(err, "failed to load the objects of truncated items")
+		return ctrl.Result{}, err
+	}
+	before := obj.DeepCopy().Status.Items
+
+	if obj.Spec.Revert {
+		return r.revert(ctx, obj, base, events)
+	}
+
+	if obj.Status.RunGeneration != 0 && obj.Status.RunGeneration != obj.Generation {
+		// the spec changed since the run in progress started, so it starts over with the new spec
+		logger.Info("Spec changed during the run, restarting it", "runGeneration", obj.Status.RunGeneration, "generation", obj.Generation)
+		obj.Status.Items = nil
+		obj.Status.Phase = ""
 	}
+	obj.Status.RunGeneration = obj.Generation
 
 	items := obj.Spec.Resources
 	if len(items) == 0 {
 		logger.Info("No resources specified, skipping")
-		if err := update.UpdateCondition(ctx, obj, ConditionComplete, ReasonNoResources, "No resources specified for processing"); err != nil {
+		obj.Status.Items = nil
+		obj.Status.Phase = ""
+		observeRun(obj)
+		setConditions(update, obj, true, false, false, ReasonNoResources, "No resources specified for processing")
+		if err := r.patchStatus(ctx, obj, base); err != nil {
 			logger.Error(err, "failed to update PreClusterDestroyCleanup status")
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{}, nil
 	}
 
-	cleanup := services.NewCleanupService(ctx, r.Client, r.Config)
-	count, err := cleanup.CleanupItems(ctx, obj.Spec.DryRun, items)
+	cleanup := services.NewCleanupService(ctx, r.Client, r.Config, r.Options)
+	if !cleanup.VerifyPlan(ctx, obj.Spec, &obj.Status) {
+		return r.planDrifted(ctx, obj, base, events)
+	}
+
+	done, err := cleanup.CleanupPhases(ctx, obj.Spec, &obj.Status)
+	events.RecordProgress(obj, before)
+	services.ObserveProgress(obj, before)
+	if !done {
+		if retrying := services.CountRetrying(obj.Status.Items); retrying > 0 {
+			setConditions(update, obj, false, true, true, ReasonRetryingItems, fmt.Sprintf("Retrying %d failed item(s) in phase %q", retrying, obj.Status.Phase))
+		} else {
+			setConditions(update, obj, false, true, false, ReasonWaitingForPhase, fmt.Sprintf("Waiting for phase %q to converge", obj.Status.Phase))
+		}
+		if err := r.patchStatus(ctx, obj, base); err != nil {
+			logger.Error(err, "failed to update PreClusterDestroyCleanup status")
+			return ctrl.Result{}, err
+		}
+		if err != nil {
+			logger.Error(err, "Error(s) occurred while waiting for phase to converge", "phase", obj.Status.Phase)
+			return ctrl.Result{}, err
+		}
+		after := requeueAfter(obj.Status.Items)
+		logger.Info("Waiting for phase to converge", "phase", obj.Status.Phase, "requeueAfter", after)
+		return ctrl.Result{RequeueAfter: after}, nil
+	}
+
+	if obj.Spec.DryRun {
+		plan := services.NewPlan(obj)
+		ref, truncated, err := services.NewPlanService(ctx, r.Client, r.Options).SavePlan(ctx, obj, plan)
+		if err != nil {
+			logger.Error(err, "failed to save plan")
+			return ctrl.Result{}, err
+		}
+		obj.Status.PlanRef = ref
+		obj.Status.PlanHash = plan.Hash
+		obj.Status.PlanTruncated = truncated
+	}
+
+	count := services.CountProcessed(obj.Status.Items)
 	if err != nil {
+		// the failed items ran out of retries, the run is not performed again until the spec or run nonce changes
 		logger.Error(err, "Error(s) occurred during processing")
-		if err := update.UpdateCondition(ctx, obj, ConditionComplete, ReasonCompletedWithErrors, fmt.Sprintf("Processed %d resources with error(s): %v", count, err)); err != nil {
+		observeRun(obj)
+		failed := services.CountItems(obj.Status.Items, cleanupv1alpha1.ItemStateFailed)
+		update.SetCondition(obj, ConditionFailed, metav1.ConditionTrue, ReasonItemsFailed, fmt.Sprintf("%d item(s) failed after up to %d retries: %v", failed, services.MaxRetries(obj.Spec), err))
+		setConditions(update, obj, false, false, true, ReasonCompletedWithErrors, fmt.Sprintf("Processed %d resources with error(s): %v", count, err))
+		services.ObserveRun(obj, ReasonCompletedWithErrors)
+		events.Event(obj, corev1.EventTypeWarning, ReasonCompletedWithErrors, fmt.Sprintf("Processed %d resources with error(s)", count))
+		if err := r.patchStatus(ctx, obj, base); err != nil {
 			logger.Error(err, "failed to update PreClusterDestroyCleanup status")
 			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, err
+		return ctrl.Result{}, reconcile.TerminalError(err)
 	}
 
-	if err := update.UpdateCondition(ctx, obj, ConditionComplete, ReasonCompletedSuccessfully, fmt.Sprintf("Processed %d resources", count)); err != nil {
+	observeRun(obj)
+	meta.RemoveStatusCondition(&obj.Status.Conditions, ConditionFailed)
+	setConditions(update, obj, true, false, false, ReasonCompletedSuccessfully, fmt.Sprintf("Processed %d resources", count))
+	services.ObserveRun(obj, ReasonCompletedSuccessfully)
+	events.Event(obj, corev1.EventTypeNormal, ReasonCompletedSuccessfully, fmt.Sprintf("Processed %d resources", count))
+	if err := r.patchStatus(ctx, obj, base); err != nil {
 		logger.Error(err, "failed to update PreClusterDestroyCleanup status")
 		return ctrl.Result{}, err
 	}
@@ -113,10 +233,125 @@ func (r *PreClusterDestroyCleanupReconciler) Reconcile(ctx context.Context, req
 	return ctrl.Result{}, nil
 }
 
+// planDrifted saves the new plan of a PreClusterDestroyCleanup whose approved plan no longer matches the cluster,
+// and reports the drift without acting on any object.
+func (r *PreClusterDestroyCleanupReconciler) planDrifted(ctx context.Context, obj, base *cleanupv1alpha1.PreClusterDestroyCleanup, events *services.EventService) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	update := services.NewUpdateService(r.Client)
+
+	ref, truncated, err := services.NewPlanService(ctx, r.Client, r.Options).SavePlan(ctx, obj, services.NewPlan(obj))
+	if err != nil {
+		logger.Error(err, "failed to save plan")
+		return ctrl.Result{}, err
+	}
+	obj.Status.PlanRef = ref
+	obj.Status.PlanTruncated = truncated
+
+	observeRun(obj)
+	message := fmt.Sprintf("Plan %s does not match the approved plan %s, review and approve the new plan", obj.Status.PlanHash, obj.Spec.ApprovedPlanHash)
+	setConditions(update, obj, false, false, false, ReasonPlanDrifted, message)
+	events.Event(obj, corev1.EventTypeWarning, ReasonPlanDrifted, message)
+	if err := r.patchStatus(ctx, obj, base); err != nil {
+		logger.Error(err, "failed to update PreClusterDestroyCleanup status")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Approved plan drifted, skipping cleanup", "name", obj.Name, "namespace", obj.Namespace, "approved", obj.Spec.ApprovedPlanHash, "current", obj.Status.PlanHash)
+	return ctrl.Result{}, nil
+}
+
+// revert scales the workloads scaled by a PreClusterDestroyCleanup back to their previous replica count.
+func (r *PreClusterDestroyCleanupReconciler) revert(ctx context.Context, obj, base *cleanupv1alpha1.PreClusterDestroyCleanup, events *services.EventService) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	update := services.NewUpdateService(r.Client)
+
+	cleanup := services.NewCleanupService(ctx, r.Client, r.Config, r.Options)
+	count, err := cleanup.Revert(ctx, obj.Spec.DryRun, &obj.Status)
+	obj.Status.Phase = ""
+	if err != nil {
+		logger.Error(err, "Error(s) occurred while reverting")
+		setConditions(update, obj, false, false, true, ReasonRevertedWithErrors, fmt.Sprintf("Restored %d resources with error(s): %v", count, err))
+		events.Event(obj, corev1.EventTypeWarning, ReasonRevertedWithErrors, fmt.Sprintf("Restored %d resources with error(s): %v", count, err))
+		if err := r.patchStatus(ctx, obj, base); err != nil {
+			logger.Error(err, "failed to update PreClusterDestroyCleanup status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	observeRun(obj)
+	setConditions(update, obj, true, false, false, ReasonReverted, fmt.Sprintf("Restored %d resources", count))
+	events.Event(obj, corev1.EventTypeNormal, ReasonReverted, fmt.Sprintf("Restored %d resources", count))
+	if err := r.patchStatus(ctx, obj, base); err != nil {
+		logger.Error(err, "failed to update PreClusterDestroyCleanup status")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Revert complete for PreClusterDestroyCleanup", "name", obj.Name, "namespace", obj.Namespace, "restored", count)
+	return ctrl.Result{}, nil
+}
+
+// requeueAfter returns how long to wait before reconciling a phase that has not converged again:
+// until the earliest retry of a failed item, or WaitRequeueInterval while items wait for their objects, whichever is sooner.
+func requeueAfter(items []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) time.Duration {
+	next := services.NextRetry(items)
+	if next == nil {
+		return WaitRequeueInterval
+	}
+	retry := max(time.Until(next.Time), MinRetryRequeueInterval)
+	if services.CountItems(items, cleanupv1alpha1.ItemStateWaiting) > 0 {
+		return min(retry, WaitRequeueInterval)
+	}
+	return retry
+}
+
+// setConditions sets the Ready, Progressing and Degraded conditions of a PreClusterDestroyCleanup with the same reason and message.
+// Ready is only true once a run fully succeeded, Progressing while a run is in progress, and Degraded while items failed.
+// The Complete condition they replaced is removed, so that waiting on it does not pass on a stale value.
+func setConditions(update *services.UpdateService, obj *cleanupv1alpha1.PreClusterDestroyCleanup, ready, progressing, degraded bool, reason string, message string) {
+	meta.RemoveStatusCondition(&obj.Status.Conditions, conditionComplete)
+	update.SetCondition(obj, ConditionReady, conditionStatus(ready), reason, message)
+	update.SetCondition(obj, ConditionProgressing, conditionStatus(progressing), reason, message)
+	update.SetCondition(obj, ConditionDegraded, conditionStatus(degraded), reason, message)
+}
+
+// patchStatus writes the changes made to the status of a PreClusterDestroyCleanup since base with a single patch,
+// after storing the full objects of the items too large for the status, see StoreService.SaveObjects.
+func (r *PreClusterDestroyCleanupReconciler) patchStatus(ctx context.Context, obj, base *cleanupv1alpha1.PreClusterDestroyCleanup) error {
+	if err := services.NewStoreService(ctx, r.Client, r.Options).SaveObjects(ctx, obj); err != nil {
+		return fmt.Errorf("failed to store the objects of truncated items: %w", err)
+	}
+	return services.NewUpdateService(r.Client).PatchStatus(ctx, obj, base)
+}
+
+// conditionStatus returns the condition status for a boolean.
+func conditionStatus(b bool) metav1.ConditionStatus {
+	if b {
+		return metav1.ConditionTrue
+	}
+	return metav1.ConditionFalse
+}
+
+// runCompleted reports whether the current generation and run nonce of a PreClusterDestroyCleanup already completed a run.
+func runCompleted(obj *cleanupv1alpha1.PreClusterDestroyCleanup) bool {
+	return obj.Status.ObservedGeneration == obj.Generation &&
+		obj.Status.ObservedRunNonce == obj.GetAnnotations()[cleanupv1alpha1.RunNonceAnnotation]
+}
+
+// observeRun records the current generation and run nonce of a PreClusterDestroyCleanup as completed,
+// so that they are not run again until the spec or the run nonce annotation changes.
+func observeRun(obj *cleanupv1alpha1.PreClusterDestroyCleanup) {
+	obj.Status.ObservedGeneration = obj.Generation
+	obj.Status.ObservedRunNonce = obj.GetAnnotations()[cleanupv1alpha1.RunNonceAnnotation]
+}
+
 // SetupWithManager sets up the controller with the Manager.
+// Only changes to the spec or annotations, like the run nonce, trigger a reconcile, not status updates or resyncs.
 func (r *PreClusterDestroyCleanupReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&cleanupv1alpha1.PreClusterDestroyCleanup{}).
+		For(&cleanupv1alpha1.PreClusterDestroyCleanup{}, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}),
+		)).
 		Named("preclusterdestroycleanup").
 		Complete(r)
 }
@@ -18,20 +18,27 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+	"github.com/MetroStar/quartz-operator/internal/services"
 )
 
 var _ = Describe("PreClusterDestroyCleanup Controller", func() {
@@ -127,9 +134,10 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 		It("should set status to Complete with NoResources reason", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &PreClusterDestroyCleanupReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
-				Config: cfg,
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -143,12 +151,12 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 				if err := k8sClient.Get(ctx, typeNamespacedName, updatedResource); err != nil {
 					return false
 				}
-				condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionComplete)
+				condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionReady)
 				return condition != nil && condition.Reason == ReasonNoResources
 			}, timeout, interval).Should(BeTrue())
 
 			// Verify the condition details
-			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionComplete)
+			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionReady)
 			Expect(condition).NotTo(BeNil())
 			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
 			Expect(condition.Reason).To(Equal(ReasonNoResources))
@@ -182,9 +190,10 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 		It("should scale the deployment to zero and update status", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &PreClusterDestroyCleanupReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
-				Config: cfg,
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -208,16 +217,205 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 				if err := k8sClient.Get(ctx, typeNamespacedName, updatedResource); err != nil {
 					return false
 				}
-				condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionComplete)
+				condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionReady)
 				return condition != nil && condition.Reason == ReasonCompletedSuccessfully
 			}, timeout, interval).Should(BeTrue())
 
 			// Verify the condition details
-			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionComplete)
+			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionReady)
 			Expect(condition).NotTo(BeNil())
 			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
 			Expect(condition.Reason).To(Equal(ReasonCompletedSuccessfully))
 			Expect(condition.Message).To(ContainSubstring("Processed"))
+			Expect(condition.ObservedGeneration).To(Equal(updatedResource.Generation))
+			Expect(meta.IsStatusConditionFalse(updatedResource.Status.Conditions, ConditionProgressing)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(updatedResource.Status.Conditions, ConditionDegraded)).To(BeTrue())
+
+			// Verify the item results were recorded
+			Expect(updatedResource.Status.Items).To(HaveLen(1))
+			Expect(updatedResource.Status.Items[0].Kind).To(Equal("Deployment"))
+			Expect(updatedResource.Status.Items[0].Objects).To(HaveLen(1))
+			Expect(updatedResource.Status.Items[0].Objects[0].Name).To(Equal(deployment.GetName()))
+			Expect(updatedResource.Status.Items[0].Objects[0].Result).To(Equal(cleanupv1alpha1.ResultScaled))
+		})
+
+		It("should remove the Complete condition of earlier versions", func() {
+			By("Setting the Complete condition the way earlier versions did")
+			resource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			meta.SetStatusCondition(&resource.Status.Conditions, metav1.Condition{
+				Type:   "Complete",
+				Status: metav1.ConditionTrue,
+				Reason: ReasonCompletedSuccessfully,
+			})
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &PreClusterDestroyCleanupReconciler{
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(meta.FindStatusCondition(resource.Status.Conditions, "Complete")).To(BeNil())
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, ConditionReady)).To(BeTrue())
+		})
+
+		It("should trace the phases and items under the span of the reconcile", func() {
+			exporter := tracetest.NewInMemoryExporter()
+			previous := otel.GetTracerProvider()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+			DeferCleanup(func() { otel.SetTracerProvider(previous) })
+
+			controllerReconciler := &PreClusterDestroyCleanupReconciler{
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			spans := map[string]tracetest.SpanStub{}
+			for _, s := range exporter.GetSpans() {
+				spans[s.Name] = s
+			}
+			Expect(spans).To(HaveKey("Reconcile"))
+			Expect(spans).To(HaveKey("CleanupPhases"))
+			Expect(spans).To(HaveKey("CleanupItem"))
+			Expect(spans).To(HaveKey("ScaleObject"))
+			Expect(spans["Reconcile"].Parent.IsValid()).To(BeFalse())
+			Expect(spans["CleanupPhases"].Parent.SpanID()).To(Equal(spans["Reconcile"].SpanContext.SpanID()))
+			Expect(spans["CleanupItem"].Parent.SpanID()).To(Equal(spans["CleanupPhases"].SpanContext.SpanID()))
+			Expect(spans["ScaleObject"].Parent.SpanID()).To(Equal(spans["CleanupItem"].SpanContext.SpanID()))
+			Expect(spans["ScaleObject"].SpanContext.TraceID()).To(Equal(spans["Reconcile"].SpanContext.TraceID()))
+		})
+
+		It("should record events on the cleanup and the scaled deployment", func() {
+			By("Reconciling the created resource")
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &PreClusterDestroyCleanupReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Config:   cfg,
+				Recorder: recorder,
+				Options:  services.Options{TargetEvents: true, Reader: k8sClient},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			events := []string{}
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Expect(events).To(HaveLen(4))
+			Expect(events[0]).To(HavePrefix("Normal " + services.EventReasonItemStarted))
+			Expect(events[1]).To(HavePrefix("Normal " + cleanupv1alpha1.ResultScaled))
+			Expect(events[2]).To(HavePrefix("Normal " + services.EventReasonItemCompleted))
+			Expect(events[3]).To(HavePrefix("Normal " + ReasonCompletedSuccessfully))
+		})
+
+		It("should not run again until the run nonce changes", func() {
+			By("Reconciling the created resource")
+			controllerReconciler := &PreClusterDestroyCleanupReconciler{
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			resource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.ObservedGeneration).To(Equal(resource.Generation))
+
+			By("Scaling the deployment back up and reconciling again")
+			d := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: deployment.GetName(), Namespace: ns.GetName()}, d)).To(Succeed())
+			d.Spec.Replicas = deployment.Spec.Replicas
+			Expect(k8sClient.Update(ctx, d)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Verify the completed run was not executed again
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: deployment.GetName(), Namespace: ns.GetName()}, d)).To(Succeed())
+			Expect(*d.Spec.Replicas).To(Equal(*deployment.Spec.Replicas))
+
+			By("Bumping the run nonce and reconciling again")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.SetAnnotations(map[string]string{cleanupv1alpha1.RunNonceAnnotation: "2"})
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: deployment.GetName(), Namespace: ns.GetName()}, d)).To(Succeed())
+			Expect(*d.Spec.Replicas).To(Equal(int32(0)))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.ObservedRunNonce).To(Equal("2"))
+		})
+
+		It("should restore the deployment to its previous replicas when revert is set", func() {
+			By("Reconciling the created resource")
+			controllerReconciler := &PreClusterDestroyCleanupReconciler{
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Setting revert and reconciling again")
+			resource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Revert = true
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Verify the deployment was restored
+			d := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: deployment.GetName(), Namespace: ns.GetName()}, d)).To(Succeed())
+			Expect(*d.Spec.Replicas).To(Equal(*deployment.Spec.Replicas))
+			Expect(d.GetAnnotations()).NotTo(HaveKey(cleanupv1alpha1.PreviousReplicasAnnotation))
+
+			// Verify the status was updated correctly
+			updatedResource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updatedResource)).To(Succeed())
+			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionReady)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(ReasonReverted))
+			Expect(condition.Message).To(ContainSubstring("Restored 1 resources"))
+			Expect(updatedResource.Status.Items[0].Objects[0].Result).To(Equal(cleanupv1alpha1.ResultRestored))
 		})
 	})
 
@@ -254,9 +452,10 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 		It("should delete the statefulset and update status", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &PreClusterDestroyCleanupReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
-				Config: cfg,
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -277,12 +476,12 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 				if err := k8sClient.Get(ctx, typeNamespacedName, updatedResource); err != nil {
 					return false
 				}
-				condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionComplete)
+				condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionReady)
 				return condition != nil && condition.Reason == ReasonCompletedSuccessfully
 			}, timeout, interval).Should(BeTrue())
 
 			// Verify the condition details
-			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionComplete)
+			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionReady)
 			Expect(condition).NotTo(BeNil())
 			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
 			Expect(condition.Reason).To(Equal(ReasonCompletedSuccessfully))
@@ -290,6 +489,86 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 		})
 	})
 
+	Context("When reconciling a resource with more objects than its status can list", func() {
+		const pods = services.MaxStatusObjects + 10
+
+		BeforeEach(func() {
+			By("creating more pods than the status of an item lists")
+			t := sharedTestEnv.WithRandomSuffix()
+			for i := range pods {
+				Expect(k8sClient.Create(ctx, t.Pod(fmt.Sprintf("test-pod-%d", i), ns.GetName()))).To(Succeed())
+			}
+
+			resource := &cleanupv1alpha1.PreClusterDestroyCleanup{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: ns.GetName(),
+				},
+				Spec: cleanupv1alpha1.PreClusterDestroyCleanupSpec{
+					DryRun: true,
+					Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+						{
+							Kind:      "Pod",
+							Namespace: ns.GetName(),
+							Action:    cleanupv1alpha1.ActionDelete,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+
+			By("Cleanup the specific resource instance PreClusterDestroyCleanup")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should store the objects outside the status and still run the approved plan", func() {
+			controllerReconciler := &PreClusterDestroyCleanupReconciler{
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
+			}
+
+			By("Planning with a dry run")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			updatedResource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updatedResource)).To(Succeed())
+			item := updatedResource.Status.Items[0]
+			Expect(item.ObjectsTruncated).To(BeTrue())
+			Expect(item.Objects).To(BeEmpty())
+			Expect(item.Results).To(HaveKeyWithValue(cleanupv1alpha1.ResultDeleted, pods))
+			Expect(updatedResource.Status.ObjectsStored).To(HaveLen(1))
+			cm := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: ns.GetName(), Name: updatedResource.Status.ObjectsStored[0]}, cm)).To(Succeed())
+
+			By("Approving the plan")
+			updatedResource.Spec.DryRun = false
+			updatedResource.Spec.ApprovedPlanHash = updatedResource.Status.PlanHash
+			Expect(k8sClient.Update(ctx, updatedResource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updatedResource)).To(Succeed())
+			Expect(updatedResource.Status.Items[0].Results).To(HaveKeyWithValue(cleanupv1alpha1.ResultDeleted, pods))
+
+			list := &corev1.PodList{}
+			Expect(k8sClient.List(ctx, list, client.InNamespace(ns.GetName()))).To(Succeed())
+			Expect(list.Items).To(BeEmpty())
+		})
+	})
+
 	Context("When reconciling a resource with DryRun mode enabled", func() {
 		var preclusterdestroycleanup *cleanupv1alpha1.PreClusterDestroyCleanup
 
@@ -329,9 +608,10 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 		It("should update status but not modify resources in DryRun mode", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &PreClusterDestroyCleanupReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
-				Config: cfg,
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -361,16 +641,241 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 				if err := k8sClient.Get(ctx, typeNamespacedName, updatedResource); err != nil {
 					return false
 				}
-				condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionComplete)
+				condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionReady)
 				return condition != nil && condition.Reason == ReasonCompletedSuccessfully
 			}, timeout, interval).Should(BeTrue())
 
 			// Verify the condition details
-			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionComplete)
+			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionReady)
 			Expect(condition).NotTo(BeNil())
 			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
 			Expect(condition.Reason).To(Equal(ReasonCompletedSuccessfully))
 			Expect(condition.Message).To(ContainSubstring("Processed"))
+
+			// Verify the plan was saved for review
+			Expect(updatedResource.Status.PlanRef).NotTo(BeNil())
+			cm := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: updatedResource.Status.PlanRef.Name, Namespace: ns.GetName()}, cm)).To(Succeed())
+			Expect(cm.Data).To(HaveKey(services.PlanJSONKey))
+			Expect(cm.Data[services.PlanTableKey]).To(ContainSubstring(deployment.GetName()))
+			Expect(cm.Data[services.PlanTableKey]).To(ContainSubstring(statefulSet.GetName()))
+			Expect(metav1.IsControlledBy(cm, updatedResource)).To(BeTrue())
+		})
+	})
+
+	Context("When reconciling a resource with an approved plan", func() {
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind PreClusterDestroyCleanup with DryRun enabled")
+			resource := &cleanupv1alpha1.PreClusterDestroyCleanup{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: ns.GetName(),
+				},
+				Spec: cleanupv1alpha1.PreClusterDestroyCleanupSpec{
+					DryRun: true,
+					Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+						{
+							Kind:      "StatefulSet",
+							Namespace: ns.GetName(),
+							Action:    cleanupv1alpha1.ActionDelete,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		It("should execute the plan once its hash is approved", func() {
+			By("Reconciling the created resource to plan")
+			controllerReconciler := &PreClusterDestroyCleanupReconciler{
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			resource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.PlanHash).NotTo(BeEmpty())
+
+			By("Approving the plan and reconciling again")
+			resource.Spec.DryRun = false
+			resource.Spec.ApprovedPlanHash = resource.Status.PlanHash
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Verify the statefulset was deleted
+			s := &appsv1.StatefulSet{}
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: statefulSet.GetName(), Namespace: ns.GetName()}, s)
+				return errors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+		})
+
+		It("should not act on any object when the plan drifted", func() {
+			By("Approving a plan that does not match the cluster")
+			controllerReconciler := &PreClusterDestroyCleanupReconciler{
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
+			}
+
+			resource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.DryRun = false
+			resource.Spec.ApprovedPlanHash = "outdated"
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Verify that statefulset was NOT deleted
+			s := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: statefulSet.GetName(), Namespace: ns.GetName()}, s)).To(Succeed())
+
+			// Verify the drift was reported with the new plan
+			updatedResource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updatedResource)).To(Succeed())
+			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionReady)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(ReasonPlanDrifted))
+			Expect(updatedResource.Status.PlanHash).NotTo(Equal("outdated"))
+			Expect(updatedResource.Status.PlanRef).NotTo(BeNil())
+		})
+	})
+
+	Context("When reconciling a resource with phases", func() {
+		var pod *corev1.Pod
+
+		BeforeEach(func() {
+			By("creating a pod that is held by a finalizer")
+			pod = sharedTestEnv.WithRandomSuffix().Pod("test-pod", ns.GetName())
+			pod.SetFinalizers([]string{"cleanup.quartz.metrostar.com/test"})
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+
+			By("creating the custom resource for the Kind PreClusterDestroyCleanup with two phases")
+			resource := &cleanupv1alpha1.PreClusterDestroyCleanup{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: ns.GetName(),
+				},
+				Spec: cleanupv1alpha1.PreClusterDestroyCleanupSpec{
+					Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+						{
+							Kind:      "Pod",
+							Namespace: ns.GetName(),
+							Name:      pod.GetName(),
+							Action:    cleanupv1alpha1.ActionDelete,
+							Phase:     "pods",
+						},
+						{
+							Kind:      "StatefulSet",
+							Namespace: ns.GetName(),
+							Name:      statefulSet.GetName(),
+							Action:    cleanupv1alpha1.ActionDelete,
+							Phase:     "workloads",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		It("should requeue until the first phase has converged", func() {
+			controllerReconciler := &PreClusterDestroyCleanupReconciler{
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
+			}
+
+			By("Reconciling while the pod is still terminating")
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(WaitRequeueInterval))
+
+			updatedResource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updatedResource)).To(Succeed())
+			Expect(updatedResource.Status.Phase).To(Equal("pods"))
+			Expect(meta.IsStatusConditionFalse(updatedResource.Status.Conditions, ConditionReady)).To(BeTrue())
+			progressing := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionProgressing)
+			Expect(progressing).NotTo(BeNil())
+			Expect(progressing.Status).To(Equal(metav1.ConditionTrue))
+			Expect(progressing.Reason).To(Equal(ReasonWaitingForPhase))
+			Expect(updatedResource.Status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateWaiting))
+			Expect(updatedResource.Status.Items[1].State).To(Equal(cleanupv1alpha1.ItemStatePending))
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(statefulSet), &appsv1.StatefulSet{})).To(Succeed())
+
+			By("Releasing the pod and reconciling again")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+			pod.SetFinalizers(nil)
+			Expect(k8sClient.Update(ctx, pod)).To(Succeed())
+
+			result, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updatedResource)).To(Succeed())
+			Expect(updatedResource.Status.Phase).To(Equal("workloads"))
+			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionReady)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(ReasonCompletedSuccessfully))
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(statefulSet), &appsv1.StatefulSet{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should restart the run when the spec changes during it", func() {
+			controllerReconciler := &PreClusterDestroyCleanupReconciler{
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
+			}
+
+			By("Reconciling while the pod is still terminating")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			updatedResource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updatedResource)).To(Succeed())
+			Expect(updatedResource.Status.RunGeneration).To(Equal(updatedResource.Generation))
+			Expect(updatedResource.Status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateWaiting))
+
+			By("Pointing the first item at another pod")
+			other := sharedTestEnv.WithRandomSuffix().Pod("other-pod", ns.GetName())
+			Expect(k8sClient.Create(ctx, other)).To(Succeed())
+			updatedResource.Spec.Resources[0].Name = other.GetName()
+			Expect(k8sClient.Update(ctx, updatedResource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updatedResource)).To(Succeed())
+			Expect(updatedResource.Status.RunGeneration).To(Equal(updatedResource.Generation))
+			Expect(updatedResource.Status.Items[0].Objects).To(HaveLen(1))
+			Expect(updatedResource.Status.Items[0].Objects[0].Name).To(Equal(other.GetName()))
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(other), &corev1.Pod{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
 		})
 	})
 
@@ -386,7 +891,8 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 						Namespace: ns.GetName(),
 					},
 					Spec: cleanupv1alpha1.PreClusterDestroyCleanupSpec{
-						DryRun: false,
+						DryRun:     false,
+						MaxRetries: sharedTestEnv.Int32Ptr(0),
 						Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
 							{
 								Kind:      "Deployment",
@@ -410,9 +916,10 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 		It("should handle errors gracefully and update status with error information", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &PreClusterDestroyCleanupReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
-				Config: cfg,
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -428,16 +935,54 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 				if err := k8sClient.Get(ctx, typeNamespacedName, updatedResource); err != nil {
 					return false
 				}
-				condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionComplete)
+				condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionDegraded)
 				return condition != nil && condition.Reason == ReasonCompletedWithErrors
 			}, timeout, interval).Should(BeTrue())
 
 			// Verify the condition details
-			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionComplete)
+			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionDegraded)
 			Expect(condition).NotTo(BeNil())
 			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
 			Expect(condition.Reason).To(Equal(ReasonCompletedWithErrors))
 			Expect(condition.Message).To(ContainSubstring("error"))
+			Expect(meta.IsStatusConditionFalse(updatedResource.Status.Conditions, ConditionReady)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(updatedResource.Status.Conditions, ConditionProgressing)).To(BeTrue())
+
+			// Verify the failure is terminal
+			Expect(err).To(MatchError(reconcile.TerminalError(nil)))
+			failed := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionFailed)
+			Expect(failed).NotTo(BeNil())
+			Expect(failed.Reason).To(Equal(ReasonItemsFailed))
+			Expect(updatedResource.Status.ObservedGeneration).To(Equal(updatedResource.Generation))
+		})
+
+		It("should requeue at the next retry of the failed items", func() {
+			By("Allowing the failed items to be retried after a minute")
+			resource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.MaxRetries = sharedTestEnv.Int32Ptr(2)
+			resource.Spec.Backoff = &metav1.Duration{Duration: time.Minute}
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &PreClusterDestroyCleanupReconciler{
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
+			}
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", WaitRequeueInterval))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", time.Minute))
+
+			updatedResource := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updatedResource)).To(Succeed())
+			progressing := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionProgressing)
+			Expect(progressing).NotTo(BeNil())
+			Expect(progressing.Reason).To(Equal(ReasonRetryingItems))
 		})
 	})
 
@@ -453,7 +998,8 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 						Namespace: ns.GetName(),
 					},
 					Spec: cleanupv1alpha1.PreClusterDestroyCleanupSpec{
-						DryRun: false,
+						DryRun:     false,
+						MaxRetries: sharedTestEnv.Int32Ptr(0),
 						Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
 							{
 								Kind:      "Deployment",
@@ -477,9 +1023,10 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 		It("should handle invalid actions and update status with error information", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &PreClusterDestroyCleanupReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
-				Config: cfg,
+				Client:  k8sClient,
+				Scheme:  k8sClient.Scheme(),
+				Config:  cfg,
+				Options: services.Options{Reader: k8sClient},
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -495,16 +1042,18 @@ var _ = Describe("PreClusterDestroyCleanup Controller", func() {
 				if err := k8sClient.Get(ctx, typeNamespacedName, updatedResource); err != nil {
 					return false
 				}
-				condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionComplete)
+				condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionDegraded)
 				return condition != nil && condition.Reason == ReasonCompletedWithErrors
 			}, timeout, interval).Should(BeTrue())
 
 			// Verify the condition details
-			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionComplete)
+			condition := meta.FindStatusCondition(updatedResource.Status.Conditions, ConditionDegraded)
 			Expect(condition).NotTo(BeNil())
 			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
 			Expect(condition.Reason).To(Equal(ReasonCompletedWithErrors))
 			Expect(condition.Message).To(ContainSubstring("error"))
+			Expect(meta.IsStatusConditionFalse(updatedResource.Status.Conditions, ConditionReady)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(updatedResource.Status.Conditions, ConditionProgressing)).To(BeTrue())
 		})
 	})
 })
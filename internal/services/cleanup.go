@@ -4,79 +4,556 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Options holds the controller level settings of a CleanupService.
+type Options struct {
+	DenyList          DenyList          // DenyList holds the namespaces and kinds that are never touched, whatever the items select
+	SuspendStrategies []SuspendStrategy // SuspendStrategies are added to DefaultSuspendStrategies for the suspend action
+	TargetEvents      bool              // TargetEvents records events on the objects acted on, as well as on the PreClusterDestroyCleanup
+	PageSize          int64             // PageSize is the number of objects listed per request, unless set by the item, DefaultPageSize if not set
+	Reader            client.Reader     // Reader reads the objects acted on from the API server instead of the cache of the client, e.g. the API reader of the manager; required to list objects
+}
+
+const (
+	DefaultBackoff    = 10 * time.Second // DefaultBackoff is the delay before the first retry of a failed item
+	MaxBackoff        = 5 * time.Minute  // MaxBackoff caps the delay between retries of a failed item
+	DefaultMaxRetries = 3                // DefaultMaxRetries is how many times a failed item is retried unless the spec says otherwise
 )
 
 // CleanupService orchestrates the cleanup actions for PreClusterDestroyCleanupItems.
 type CleanupService struct {
-	lookup *LookupService
-	scale  *ScaleService
-	delete *DeleteService
-	logger logr.Logger
+	lookup  *LookupService
+	scale   *ScaleService
+	delete  *DeleteService
+	suspend *SuspendService
+	patch   *PatchService
+	logger  logr.Logger
 }
 
 // NewCleanupService creates a new CleanupService instance.
-func NewCleanupService(ctx context.Context, client client.Client, config *rest.Config) *CleanupService {
-	lookup := NewLookupService(ctx, client, config)
+// If opts.Reader is set, every get and list of the services goes through it, see uncachedClient.
+// Without it, items that list objects fail, see ListResourcePages.
+func NewCleanupService(ctx context.Context, client client.Client, config *rest.Config, opts Options) *CleanupService {
+	if opts.Reader != nil {
+		client = &uncachedClient{Client: client, reader: opts.Reader}
+	}
+	lookup := NewLookupService(ctx, client, config, opts)
 	return &CleanupService{
-		lookup: lookup,
-		scale:  NewScaleService(ctx, client, lookup),
-		delete: NewDeleteService(ctx, client, lookup),
-		logger: log.FromContext(ctx),
+		lookup:  lookup,
+		scale:   NewScaleService(ctx, client, lookup, opts.DenyList),
+		delete:  NewDeleteService(ctx, client, lookup, opts.DenyList),
+		suspend: NewSuspendService(ctx, client, lookup, opts.DenyList, opts.SuspendStrategies),
+		patch:   NewPatchService(ctx, client, lookup, opts.DenyList),
+		logger:  log.FromContext(ctx),
 	}
 }
 
-// CleanupItems processes a list of PreClusterDestroyCleanupItems.
-// It performs the specified action (scale to zero or delete) on each item.
-// It returns the count of successfully processed items and any errors encountered.
-// If dryRun is true, it simulates the actions without making actual changes.
-func (s *CleanupService) CleanupItems(ctx context.Context, dryRun bool, items []cleanupv1alpha1.PreClusterDestroyCleanupItem) (int, error) {
-	count := 0
-	errs := []error{}
-	for _, item := range items {
-		if item.Kind == "" {
-			errs = append(errs, fmt.Errorf("kind must be specified for item: %v", item))
-			continue
+// uncachedClient reads with reader instead of the cache of the manager client. The cache cannot page through lists,
+// as it ignores continue tokens, nor filter by field selectors without an index, and it would start an informer
+// for every kind the cleanup lists, holding all their objects in memory.
+type uncachedClient struct {
+	client.Client
+	reader client.Reader
+}
+
+// Get reads an object from the API server.
+func (c *uncachedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return c.reader.Get(ctx, key, obj, opts...)
+}
+
+// List reads a list of objects from the API server.
+func (c *uncachedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.reader.List(ctx, list, opts...)
+}
+
+// CleanupPhases processes the items of a PreClusterDestroyCleanup phase by phase, recording progress in status.
+// Phases run in the order they first appear in spec.resources. The items of a phase are executed once,
+// and the next phase starts only after every object touched by the current phase has been deleted or scaled down.
+// It returns true once all phases have finished, or processing stopped because of errors.
+// In dry run mode, nothing depends on the objects of a phase being gone, so every phase is planned even after errors.
+// Items are not waited on in dry run mode, or in the last phase as nothing depends on them unless waitForDeletion is set.
+// Items that do not converge within their timeout are failed. Items whose action fails are retried with an exponential
+// backoff, up to spec.maxRetries times, without performing the items that already succeeded again.
+func (s *CleanupService) CleanupPhases(ctx context.Context, spec cleanupv1alpha1.PreClusterDestroyCleanupSpec, status *cleanupv1alpha1.PreClusterDestroyCleanupStatus) (bool, error) {
+	ctx, span := StartSpan(ctx, "CleanupPhases", AttributeDryRun.Bool(spec.DryRun), attribute.Int("quartz.items", len(spec.Resources)))
+	done, err := s.cleanupPhases(ctx, spec, status)
+	span.SetAttributes(attribute.String("quartz.phase", status.Phase), attribute.Bool("quartz.done", done))
+	EndSpan(span, err)
+	return done, err
+}
+
+// cleanupPhases processes the items of a PreClusterDestroyCleanup phase by phase, recording progress in status.
+func (s *CleanupService) cleanupPhases(ctx context.Context, spec cleanupv1alpha1.PreClusterDestroyCleanupSpec, status *cleanupv1alpha1.PreClusterDestroyCleanupStatus) (bool, error) {
+	items := spec.Resources
+	if !phasesInProgress(items, status.Items) {
+		status.Items = pendingItems(items)
+	}
+	approved := spec.ApprovedPlanHash != "" && !spec.DryRun
+
+	phases := phaseOrder(items)
+	for p, phase := range phases {
+		status.Phase = phase
+		last := p == len(phases)-1
+
+		errs := []error{}
+		retrying := false
+		for i, item := range items {
+			if item.Phase != phase || status.Items[i].State != cleanupv1alpha1.ItemStatePending {
+				continue
+			}
+
+			attempts := status.Items[i].Attempts
+			if next := status.Items[i].NextRetryTime; next != nil && time.Now().Before(next.Time) {
+				retrying = true
+				continue
+			}
+
+			s.logger.Info("Processing item", "phase", phase, "index", i, "kind", item.Kind, "attempt", attempts+1)
+			itemCtx := ctx
+			if approved {
+				// only the objects of the approved plan are acted on, not objects created since
+				itemCtx = WithApprovedObjects(ctx, status.Items[i].ApprovedUIDs)
+			}
+			st, err := s.CleanupItem(itemCtx, spec.DryRun, item)
+			now := metav1.Now()
+			st.Index = i
+			st.Phase = phase
+			st.StartTime = &now
+			st.Attempts = attempts + 1
+			st.ApprovedUIDs = status.Items[i].ApprovedUIDs
+			st.Objects = mergeObjects(status.Items[i].Objects, st.Objects)
+			switch {
+			case err != nil && st.Attempts <= MaxRetries(spec):
+				next := metav1.NewTime(now.Add(RetryBackoff(spec, st.Attempts)))
+				s.logger.Info("Item failed, retrying", "index", i, "kind", item.Kind, "attempt", st.Attempts, "retryAt", next, "error", err.Error())
+				st.State = cleanupv1alpha1.ItemStatePending
+				st.NextRetryTime = &next
+				retrying = true
+			case err != nil:
+				st.State = cleanupv1alpha1.ItemStateFailed
+				errs = append(errs, err)
+			case spec.DryRun, last && !item.WaitForDeletion && item.ForceAfter == nil:
+				st.State = cleanupv1alpha1.ItemStateCompleted
+			default:
+				st.State = cleanupv1alpha1.ItemStateWaiting
+			}
+			status.Items[i] = st
+		}
+
+		if len(errs) > 0 && !spec.DryRun {
+			return true, fmt.Errorf("%d errors occurred during processing of phase %q: %w", len(errs), phase, errors.Join(errs...))
+		}
+
+		converged := true
+		for i := range status.Items {
+			st := &status.Items[i]
+			if st.Phase != phase || st.State != cleanupv1alpha1.ItemStateWaiting {
+				continue
+			}
+
+			if err := s.updateProgress(ctx, items[i], st); err != nil {
+				return false, fmt.Errorf("failed to check progress of item %d: %w", i, err)
+			}
+
+			switch st.State {
+			case cleanupv1alpha1.ItemStateFailed:
+				errs = append(errs, errors.New(st.Message))
+			case cleanupv1alpha1.ItemStateWaiting:
+				converged = false
+			}
+		}
+
+		if len(errs) > 0 {
+			return true, fmt.Errorf("%d errors occurred during processing of phase %q: %w", len(errs), phase, errors.Join(errs...))
+		}
+
+		if !converged || retrying {
+			s.logger.Info("Waiting for phase to converge", "phase", phase, "retrying", retrying)
+			return false, nil
+		}
+	}
+
+	if spec.DryRun {
+		return true, failedPhases(phases, status.Items)
+	}
+	return true, nil
+}
+
+// failedPhases returns the errors of the failed items, one per phase with failed items, or nil if none failed.
+func failedPhases(phases []string, statuses []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) error {
+	phaseErrs := []error{}
+	for _, phase := range phases {
+		errs := []error{}
+		for _, st := range statuses {
+			if st.Phase == phase && st.State == cleanupv1alpha1.ItemStateFailed {
+				errs = append(errs, errors.New(st.Message))
+			}
 		}
+		if len(errs) > 0 {
+			phaseErrs = append(phaseErrs, fmt.Errorf("%d errors occurred during processing of phase %q: %w", len(errs), phase, errors.Join(errs...)))
+		}
+	}
+	return errors.Join(phaseErrs...)
+}
+
+// pendingItems returns the status of items that have not been performed yet.
+func pendingItems(items []cleanupv1alpha1.PreClusterDestroyCleanupItem) []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus {
+	statuses := make([]cleanupv1alpha1.PreClusterDestroyCleanupItemStatus, len(items))
+	for i, item := range items {
+		statuses[i] = cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{
+			Index:  i,
+			Kind:   item.Kind,
+			Action: item.Action,
+			Phase:  item.Phase,
+			State:  cleanupv1alpha1.ItemStatePending,
+		}
+	}
+	return statuses
+}
+
+// MaxRetries returns how many times a failed item of a PreClusterDestroyCleanup is retried, DefaultMaxRetries if not set.
+func MaxRetries(spec cleanupv1alpha1.PreClusterDestroyCleanupSpec) int32 {
+	if spec.MaxRetries == nil {
+		return DefaultMaxRetries
+	}
+	return *spec.MaxRetries
+}
+
+// RetryBackoff returns how long to wait before retrying an item that failed on the given attempt.
+// The backoff of the spec, or DefaultBackoff, is doubled for each further attempt, up to MaxBackoff.
+func RetryBackoff(spec cleanupv1alpha1.PreClusterDestroyCleanupSpec, attempt int32) time.Duration {
+	backoff := DefaultBackoff
+	if spec.Backoff != nil {
+		backoff = spec.Backoff.Duration
+	}
+	for i := int32(1); i < attempt && backoff < MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, MaxBackoff)
+}
+
+// updateProgress checks the objects touched by a waiting item and updates its state.
+// The item is completed once no objects are pending, and failed once its timeout has elapsed.
+func (s *CleanupService) updateProgress(ctx context.Context, item cleanupv1alpha1.PreClusterDestroyCleanupItem, status *cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) error {
+	pending, err := s.PendingObjects(ctx, *status)
+	if err != nil {
+		return err
+	}
 
-		gvk, err := s.lookup.LookupGroupKind(item.Kind)
+	if len(pending) == 0 {
+		markPending(status.Objects, pending, false)
+		status.State = cleanupv1alpha1.ItemStateCompleted
+		status.Message = ""
+		return nil
+	}
+
+	if item.ForceAfter != nil && item.Action == cleanupv1alpha1.ActionDelete && status.StartTime != nil && time.Since(status.StartTime.Time) > item.ForceAfter.Duration {
+		forced, err := s.delete.RemoveFinalizers(ctx, pending)
+		recordForced(status.Objects, forced)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("failed to lookup group and kind for %s: %w", item.Kind, err))
+			return err
+		}
+	}
+
+	if item.Timeout != nil && status.StartTime != nil && time.Since(status.StartTime.Time) > item.Timeout.Duration {
+		s.logger.Info("Timed out waiting for item to converge", "index", status.Index, "kind", status.Kind, "pending", len(pending))
+		markPending(status.Objects, pending, true)
+		status.State = cleanupv1alpha1.ItemStateFailed
+		status.Message = fmt.Sprintf("timed out after %s waiting for %d object(s) to converge", item.Timeout.Duration, len(pending))
+		return nil
+	}
+
+	markPending(status.Objects, pending, false)
+	status.Message = fmt.Sprintf("Waiting for %d object(s) to converge", len(pending))
+	return nil
+}
+
+// mergeObjects returns the objects reported by the latest attempt of an item, followed by the objects reported by
+// earlier attempts that the latest one did not report, so that they are still waited on and can be reverted.
+func mergeObjects(earlier, latest []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus {
+	reported := make(map[string]bool, len(latest))
+	for _, o := range latest {
+		reported[objectKey(o)] = true
+	}
+	for _, o := range earlier {
+		if !reported[objectKey(o)] {
+			latest = append(latest, o)
+		}
+	}
+	return latest
+}
+
+// recordForced replaces the entries of objects whose finalizers were removed with their forced status.
+func recordForced(objects, forced []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) {
+	for _, f := range forced {
+		idx := slices.IndexFunc(objects, func(o cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) bool {
+			return o.APIVersion == f.APIVersion && o.Kind == f.Kind && o.Namespace == f.Namespace && o.Name == f.Name
+		})
+		if idx >= 0 {
+			objects[idx] = f
+		}
+	}
+}
+
+// markPending copies the messages of pending objects onto the matching entries of objects, clearing the
+// messages of objects that are no longer pending. If failed is true, the pending objects are marked as failed.
+func markPending(objects, pending []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, failed bool) {
+	for i := range objects {
+		o := &objects[i]
+		if o.Result != cleanupv1alpha1.ResultDeleted && o.Result != cleanupv1alpha1.ResultScaled && o.Result != cleanupv1alpha1.ResultForced {
 			continue
 		}
 
-		switch item.Action {
-		case cleanupv1alpha1.ActionScaleToZero:
-			s.logger.Info("Scaling to zero", "kind", gvk.Kind, "namespace", item.Namespace, "name", item.Name)
-			replicas := int32(0)
-			c, err := s.scale.ScaleItem(ctx, dryRun, gvk, item, &replicas)
-			if err != nil {
-				errs = append(errs, fmt.Errorf("failed to scale %s %s/%s to zero: %w", gvk.Kind, item.Namespace, item.Name, err))
+		idx := slices.IndexFunc(pending, func(p cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) bool {
+			return p.APIVersion == o.APIVersion && p.Kind == o.Kind && p.Namespace == o.Namespace && p.Name == o.Name
+		})
+		if idx < 0 {
+			o.Message = ""
+			continue
+		}
+
+		o.Message = pending[idx].Message
+		if failed {
+			o.Result = cleanupv1alpha1.ResultFailed
+			o.Message = "timed out: " + o.Message
+		}
+	}
+}
+
+// PendingObjects returns the objects touched by an item that have not yet reached the state requested by its action.
+func (s *CleanupService) PendingObjects(ctx context.Context, status cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	switch status.Action {
+	case cleanupv1alpha1.ActionScaleToZero:
+		return s.scale.PendingScale(ctx, status.Objects)
+	case cleanupv1alpha1.ActionDelete:
+		return s.delete.PendingDeletion(ctx, status.Objects)
+	default:
+		return nil, nil
+	}
+}
+
+// phaseOrder returns the names of the phases used by items, in the order they first appear.
+func phaseOrder(items []cleanupv1alpha1.PreClusterDestroyCleanupItem) []string {
+	phases := []string{}
+	for _, item := range items {
+		if !slices.Contains(phases, item.Phase) {
+			phases = append(phases, item.Phase)
+		}
+	}
+	return phases
+}
+
+// phasesInProgress reports whether statuses record a run of items that has started but not yet finished.
+// A run is finished once every item completed or any item failed.
+func phasesInProgress(items []cleanupv1alpha1.PreClusterDestroyCleanupItem, statuses []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) bool {
+	if len(statuses) != len(items) {
+		return false
+	}
+
+	finished := true
+	for i, st := range statuses {
+		if st.Phase != items[i].Phase || st.Action != items[i].Action {
+			return false
+		}
+
+		switch st.State {
+		case cleanupv1alpha1.ItemStateFailed:
+			return false
+		case cleanupv1alpha1.ItemStatePending, cleanupv1alpha1.ItemStateWaiting:
+			finished = false
+		}
+	}
+
+	return !finished
+}
+
+// VerifyPlan checks that the objects a PreClusterDestroyCleanup would act on still match the plan approved by
+// spec.approvedPlanHash, by planning the phases again with a dry run, the way the plan was made. It returns true if the
+// cleanup may proceed: nothing needs approval, a run has already started, or the plan is unchanged.
+// When the plan is unchanged, status starts a run recording the objects of the approved plan of each item, and the
+// run acts on no other object, whichever phase it is in, see WithApprovedObjects.
+// If the plan drifted, status records the new plan, to be reviewed and approved again, and it returns false.
+func (s *CleanupService) VerifyPlan(ctx context.Context, spec cleanupv1alpha1.PreClusterDestroyCleanupSpec, status *cleanupv1alpha1.PreClusterDestroyCleanupStatus) bool {
+	if spec.ApprovedPlanHash == "" || spec.DryRun || phasesInProgress(spec.Resources, status.Items) {
+		return true
+	}
+
+	// plan like a dry run of the cleanup, whose retries, if any, would have run out before the plan was saved
+	dryRun := *spec.DeepCopy()
+	dryRun.DryRun = true
+	noRetries := int32(0)
+	dryRun.MaxRetries = &noRetries
+	planned := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+	if _, err := s.CleanupPhases(ctx, dryRun, planned); err != nil {
+		// failures are part of the plan, the hash tells whether they were approved
+		s.logger.Info("Planning encountered errors", "error", err.Error())
+	}
+
+	hash := PlanHash(planned.Items)
+	status.PlanHash = hash
+	if hash == spec.ApprovedPlanHash {
+		status.Items = pendingItems(spec.Resources)
+		for i := range status.Items {
+			status.Items[i].ApprovedUIDs = ApprovedUIDs(planned.Items[i])
+		}
+		status.Phase = ""
+		return true
+	}
+
+	s.logger.Info("Plan drifted since it was approved", "approved", spec.ApprovedPlanHash, "current", hash)
+	status.Items = planned.Items
+	status.Phase = ""
+	return false
+}
+
+// Revert scales the workloads recorded by the scale items in status back to the replica count recorded in their
+// annotation. Whether an object is restored depends on the annotation rather than its result, as an object that was
+// scaled is recorded as failed if it then timed out. Objects skipped by the cleanup, e.g. protected ones, are left untouched.
+// The objects in status are updated with the result of the restore, except in dry run mode where status is left unchanged.
+// It returns the number of objects restored, or that would be restored in dry run mode, and any errors encountered.
+func (s *CleanupService) Revert(ctx context.Context, dryRun bool, status *cleanupv1alpha1.PreClusterDestroyCleanupStatus) (int, error) {
+	count := 0
+	errs := []error{}
+	for i := range status.Items {
+		if status.Items[i].Action != cleanupv1alpha1.ActionScaleToZero {
+			continue
+		}
+
+		for j, o := range status.Items[i].Objects {
+			if o.Result == cleanupv1alpha1.ResultRestored {
+				count++
+				continue
 			}
-			count += c
-		case cleanupv1alpha1.ActionDelete:
-			s.logger.Info("Deleting item", "kind", gvk.Kind, "namespace", item.Namespace, "name", item.Name)
-			c, err := s.delete.DeleteItem(ctx, dryRun, gvk, item)
+			if o.Result == cleanupv1alpha1.ResultSkipped {
+				continue
+			}
+
+			restored, err := s.scale.RestoreObject(ctx, dryRun, o)
 			if err != nil {
-				errs = append(errs, fmt.Errorf("failed to delete %s %s/%s: %w", gvk.Kind, item.Namespace, item.Name, err))
+				errs = append(errs, err)
+			}
+			if restored.Result == cleanupv1alpha1.ResultRestored {
+				count++
+			}
+			if !dryRun {
+				status.Items[i].Objects[j] = restored
 			}
-			count += c
-		case cleanupv1alpha1.ActionUnknown:
-			errs = append(errs, fmt.Errorf("action must be specified for item: %v", item))
-		default:
-			errs = append(errs, fmt.Errorf("unsupported action for item: %v", item))
 		}
 	}
 
 	if len(errs) > 0 {
-		return count, fmt.Errorf("%d errors occurred during processing: %w", len(errs), errors.Join(errs...))
+		return count, fmt.Errorf("%d errors occurred while reverting: %w", len(errs), errors.Join(errs...))
 	}
-
 	return count, nil
 }
+
+// CleanupItem performs the action of a single PreClusterDestroyCleanupItem.
+// It returns the status of the item, including each object touched, and any error encountered.
+// Each item is performed in a span of its own.
+func (s *CleanupService) CleanupItem(ctx context.Context, dryRun bool, item cleanupv1alpha1.PreClusterDestroyCleanupItem) (cleanupv1alpha1.PreClusterDestroyCleanupItemStatus, error) {
+	ctx, span := StartSpan(ctx, "CleanupItem",
+		AttributeKind.String(item.Kind),
+		AttributeAction.String(item.Action),
+		AttributeNamespace.String(item.Namespace),
+		AttributeName.String(item.Name),
+		AttributeDryRun.Bool(dryRun),
+	)
+	status, err := s.cleanupItem(ctx, dryRun, item)
+	span.SetAttributes(attribute.Int("quartz.objects", len(status.Objects)))
+	EndSpan(span, err)
+	return status, err
+}
+
+// cleanupItem performs the action of a single PreClusterDestroyCleanupItem.
+func (s *CleanupService) cleanupItem(ctx context.Context, dryRun bool, item cleanupv1alpha1.PreClusterDestroyCleanupItem) (cleanupv1alpha1.PreClusterDestroyCleanupItemStatus, error) {
+	status := cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{
+		Action: item.Action,
+		Kind:   item.Kind,
+	}
+
+	// an item without a kind deletes the resources of every kind in its category
+	byCategory := item.Kind == "" && item.Category != "" && item.Action == cleanupv1alpha1.ActionDelete
+	if item.Kind == "" && !byCategory {
+		err := fmt.Errorf("kind must be specified for item: %v", item)
+		status.Message = err.Error()
+		return status, err
+	}
+
+	gvk := schema.GroupVersionKind{}
+	if !byCategory {
+		var err error
+		gvk, err = s.lookup.LookupGroupKind(item.Kind)
+		if err != nil {
+			err = fmt.Errorf("failed to lookup group and kind for %s: %w", item.Kind, err)
+			status.Message = err.Error()
+			return status, err
+		}
+		status.Group = gvk.Group
+		status.Version = gvk.Version
+		status.Kind = gvk.Kind
+	}
+
+	target, err := s.lookup.LookupTarget(ctx, gvk, item)
+	if err != nil {
+		status.Message = err.Error()
+		return status, err
+	}
+	if !slices.Equal(target.Namespaces, []string{""}) {
+		status.Namespaces = target.Namespaces
+	}
+	if SelectsCategory(gvk, item) {
+		for _, k := range target.Kinds {
+			status.DiscoveredKinds = append(status.DiscoveredKinds, metav1.GroupVersionKind(k))
+		}
+	}
+
+	switch item.Action {
+	case cleanupv1alpha1.ActionScaleToZero:
+		s.logger.Info("Scaling to zero", "kind", gvk.Kind, "namespace", item.Namespace, "name", item.Name)
+		replicas := int32(0)
+		status.Objects, err = s.scale.ScaleItem(ctx, dryRun, target, item, &replicas)
+		if err != nil {
+			err = fmt.Errorf("failed to scale %s %s/%s to zero: %w", gvk.Kind, item.Namespace, item.Name, err)
+		}
+	case cleanupv1alpha1.ActionDelete:
+		s.logger.Info("Deleting item", "kind", gvk.Kind, "namespace", item.Namespace, "name", item.Name)
+		status.Objects, err = s.delete.DeleteItem(ctx, dryRun, target, item)
+		if err != nil {
+			err = fmt.Errorf("failed to delete %s %s/%s: %w", gvk.Kind, item.Namespace, item.Name, err)
+		}
+	case cleanupv1alpha1.ActionSuspend:
+		s.logger.Info("Suspending item", "kind", gvk.Kind, "namespace", item.Namespace, "name", item.Name)
+		status.Objects, err = s.suspend.SuspendItem(ctx, dryRun, target, item)
+		if err != nil {
+			err = fmt.Errorf("failed to suspend %s %s/%s: %w", gvk.Kind, item.Namespace, item.Name, err)
+		}
+	case cleanupv1alpha1.ActionPatch:
+		s.logger.Info("Patching item", "kind", gvk.Kind, "namespace", item.Namespace, "name", item.Name)
+		status.Objects, err = s.patch.PatchItem(ctx, dryRun, target, item)
+		if err != nil {
+			err = fmt.Errorf("failed to patch %s %s/%s: %w", gvk.Kind, item.Namespace, item.Name, err)
+		}
+	case cleanupv1alpha1.ActionUnknown:
+		err = fmt.Errorf("action must be specified for item: %v", item)
+	default:
+		err = fmt.Errorf("unsupported action for item: %v", item)
+	}
+
+	if err != nil {
+		status.Message = err.Error()
+	}
+	return status, err
+}
@@ -2,11 +2,14 @@ package services
 
 import (
 	"context"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
@@ -38,7 +41,7 @@ var _ = Describe("CleanupService", func() {
 		Expect(c.Create(ctx, statefulSet)).To(Succeed())
 
 		// Initialize the CleanupService
-		cleanupService = NewCleanupService(ctx, c, t.Cfg)
+		cleanupService = NewCleanupService(ctx, c, t.Cfg, Options{Reader: c})
 	})
 
 	AfterEach(func() {
@@ -46,7 +49,7 @@ var _ = Describe("CleanupService", func() {
 		Expect(c.Delete(ctx, ns)).To(Succeed())
 	})
 
-	Describe("CleanupItems", func() {
+	Describe("CleanupItem", func() {
 		It("should scale resources to zero when action is ScaleToZero", func() {
 			items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
 				{
@@ -57,9 +60,10 @@ var _ = Describe("CleanupService", func() {
 				},
 			}
 
-			count, err := cleanupService.CleanupItems(ctx, false, items)
+			statuses, err := runItems(ctx, cleanupService, false, items)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(1))
+			Expect(statuses).To(HaveLen(len(items)))
+			Expect(CountProcessed(statuses)).To(Equal(1))
 
 			// Verify the deployment was scaled to zero
 			d := &appsv1.Deployment{}
@@ -78,9 +82,10 @@ var _ = Describe("CleanupService", func() {
 				},
 			}
 
-			count, err := cleanupService.CleanupItems(ctx, false, items)
+			statuses, err := runItems(ctx, cleanupService, false, items)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(1))
+			Expect(statuses).To(HaveLen(len(items)))
+			Expect(CountProcessed(statuses)).To(Equal(1))
 
 			// Verify the deployment was deleted
 			d := &appsv1.Deployment{}
@@ -105,9 +110,10 @@ var _ = Describe("CleanupService", func() {
 				},
 			}
 
-			count, err := cleanupService.CleanupItems(ctx, false, items)
+			statuses, err := runItems(ctx, cleanupService, false, items)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(2))
+			Expect(statuses).To(HaveLen(len(items)))
+			Expect(CountProcessed(statuses)).To(Equal(2))
 
 			// Verify the deployment was scaled to zero
 			d := &appsv1.Deployment{}
@@ -122,6 +128,48 @@ var _ = Describe("CleanupService", func() {
 			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
 		})
 
+		It("should record the resolved kind and touched objects for each item", func() {
+			items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				{
+					Kind:      "Deployment",
+					Namespace: ns.GetName(),
+					Name:      deployment.GetName(),
+					Action:    cleanupv1alpha1.ActionScaleToZero,
+				},
+				{
+					Kind:      "StatefulSet",
+					Namespace: ns.GetName(),
+					Name:      "missing-statefulset",
+					Action:    cleanupv1alpha1.ActionDelete,
+				},
+			}
+
+			statuses, err := runItems(ctx, cleanupService, false, items)
+			Expect(err).To(HaveOccurred())
+			Expect(statuses).To(HaveLen(2))
+
+			Expect(statuses[0].Index).To(Equal(0))
+			Expect(statuses[0].Group).To(Equal("apps"))
+			Expect(statuses[0].Version).To(Equal("v1"))
+			Expect(statuses[0].Kind).To(Equal("Deployment"))
+			Expect(statuses[0].Action).To(Equal(cleanupv1alpha1.ActionScaleToZero))
+			Expect(statuses[0].Message).To(BeEmpty())
+			Expect(statuses[0].Namespaces).To(Equal([]string{ns.GetName()}))
+			Expect(statuses[0].Objects).To(HaveLen(1))
+			Expect(statuses[0].Objects[0].Namespace).To(Equal(ns.GetName()))
+			Expect(statuses[0].Objects[0].Name).To(Equal(deployment.GetName()))
+			Expect(statuses[0].Objects[0].UID).To(Equal(deployment.GetUID()))
+			Expect(statuses[0].Objects[0].Result).To(Equal(cleanupv1alpha1.ResultScaled))
+
+			Expect(statuses[1].Index).To(Equal(1))
+			Expect(statuses[1].Kind).To(Equal("StatefulSet"))
+			Expect(statuses[1].Message).NotTo(BeEmpty())
+			Expect(statuses[1].Objects).To(HaveLen(1))
+			Expect(statuses[1].Objects[0].Name).To(Equal("missing-statefulset"))
+			Expect(statuses[1].Objects[0].Result).To(Equal(cleanupv1alpha1.ResultFailed))
+			Expect(statuses[1].Objects[0].Message).NotTo(BeEmpty())
+		})
+
 		It("should not make changes in dry run mode", func() {
 			items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
 				{
@@ -138,9 +186,10 @@ var _ = Describe("CleanupService", func() {
 				},
 			}
 
-			count, err := cleanupService.CleanupItems(ctx, true, items)
+			statuses, err := runItems(ctx, cleanupService, true, items)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(2))
+			Expect(statuses).To(HaveLen(len(items)))
+			Expect(CountProcessed(statuses)).To(Equal(2))
 
 			// Verify the deployment was not changed
 			d := &appsv1.Deployment{}
@@ -154,6 +203,72 @@ var _ = Describe("CleanupService", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("should report the kinds discovered by category with the version used", func() {
+			t := testEnv.WithRandomSuffix()
+			category := t.FormatName("teardown")
+			crd := t.CustomResourceDefinition("Widget", category, "v1alpha1", "v1")
+			Expect(c.Create(ctx, crd)).To(Succeed())
+			DeferCleanup(func() { Expect(c.Delete(ctx, crd)).To(Succeed()) })
+			Eventually(func() bool {
+				Expect(c.Get(ctx, client.ObjectKeyFromObject(crd), crd)).To(Succeed())
+				return CrdEstablished(crd)
+			}).Should(BeTrue())
+
+			items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				{
+					Kind:      CustomResourceDefinitionKind,
+					Category:  category,
+					Namespace: ns.GetName(),
+					Action:    cleanupv1alpha1.ActionDelete,
+				},
+			}
+
+			Eventually(func() []metav1.GroupVersionKind {
+				statuses, err := runItems(ctx, NewCleanupService(ctx, c, t.Cfg, Options{Reader: c}), true, items)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(statuses).To(HaveLen(1))
+				return statuses[0].DiscoveredKinds
+			}).Should(ConsistOf(metav1.GroupVersionKind{Group: crd.Spec.Group, Version: "v1", Kind: "Widget"}))
+		})
+
+		It("should delete the resources of every kind in the category of an item without a kind", func() {
+			t := testEnv.WithRandomSuffix()
+			category := t.FormatName("teardown")
+			crd := t.CustomResourceDefinition("Widget", category, "v1")
+			Expect(c.Create(ctx, crd)).To(Succeed())
+			DeferCleanup(func() { Expect(c.Delete(ctx, crd)).To(Succeed()) })
+
+			widget := &unstructured.Unstructured{}
+			widget.SetAPIVersion(crd.Spec.Group + "/v1")
+			widget.SetKind("Widget")
+			widget.SetNamespace(ns.GetName())
+			widget.SetName("test-widget")
+			Eventually(func() error { return c.Create(ctx, widget) }).Should(Succeed())
+
+			items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				{
+					Category:  category,
+					Namespace: ns.GetName(),
+					Action:    cleanupv1alpha1.ActionDelete,
+				},
+			}
+
+			statuses, err := runItems(ctx, NewCleanupService(ctx, c, t.Cfg, Options{Reader: c}), false, items)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(statuses).To(HaveLen(1))
+			Expect(statuses[0].Kind).To(BeEmpty())
+			Expect(statuses[0].DiscoveredKinds).To(ConsistOf(metav1.GroupVersionKind{Group: crd.Spec.Group, Version: "v1", Kind: "Widget"}))
+			Expect(statuses[0].Objects).To(HaveLen(1))
+			Expect(statuses[0].Objects[0].Name).To(Equal(widget.GetName()))
+			Expect(statuses[0].Objects[0].Result).To(Equal(cleanupv1alpha1.ResultDeleted))
+
+			By("requiring a kind for other actions")
+			items[0].Action = cleanupv1alpha1.ActionScaleToZero
+			_, err = runItems(ctx, cleanupService, false, items)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("kind must be specified for item"))
+		})
+
 		It("should handle missing kind gracefully", func() {
 			items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
 				{
@@ -163,9 +278,11 @@ var _ = Describe("CleanupService", func() {
 				},
 			}
 
-			_, err := cleanupService.CleanupItems(ctx, false, items)
+			statuses, err := runItems(ctx, cleanupService, false, items)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("kind must be specified for item"))
+			Expect(statuses).To(HaveLen(1))
+			Expect(statuses[0].Message).To(ContainSubstring("kind must be specified for item"))
 		})
 
 		It("should handle unknown action gracefully", func() {
@@ -178,9 +295,553 @@ var _ = Describe("CleanupService", func() {
 				},
 			}
 
-			_, err := cleanupService.CleanupItems(ctx, false, items)
+			_, err := runItems(ctx, cleanupService, false, items)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("action must be specified for item"))
 		})
 	})
+
+	Describe("CleanupPhases", func() {
+		It("should run phases in order of first appearance", func() {
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{
+				Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+					{
+						Kind:      "StatefulSet",
+						Namespace: ns.GetName(),
+						Name:      statefulSet.GetName(),
+						Action:    cleanupv1alpha1.ActionDelete,
+						Phase:     "delete",
+					},
+					{
+						Kind:      "Deployment",
+						Namespace: ns.GetName(),
+						Name:      deployment.GetName(),
+						Action:    cleanupv1alpha1.ActionScaleToZero,
+						Phase:     "quiesce",
+					},
+				},
+			}
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+
+			done, err := cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(status.Phase).To(Equal("quiesce"))
+			Expect(status.Items).To(HaveLen(2))
+			Expect(status.Items[0].Phase).To(Equal("delete"))
+			Expect(status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateCompleted))
+			Expect(status.Items[1].Phase).To(Equal("quiesce"))
+			Expect(status.Items[1].State).To(Equal(cleanupv1alpha1.ItemStateCompleted))
+			Expect(CountProcessed(status.Items)).To(Equal(2))
+		})
+
+		It("should not start the next phase until the previous phase has converged", func() {
+			pod := testEnv.WithRandomSuffix().Pod("blocked-pod", ns.GetName())
+			pod.SetFinalizers([]string{"cleanup.quartz.metrostar.com/test"})
+			Expect(c.Create(ctx, pod)).To(Succeed())
+
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{
+				Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+					{
+						Kind:      "Pod",
+						Namespace: ns.GetName(),
+						Name:      pod.GetName(),
+						Action:    cleanupv1alpha1.ActionDelete,
+						Phase:     "first",
+					},
+					{
+						Kind:      "Deployment",
+						Namespace: ns.GetName(),
+						Name:      deployment.GetName(),
+						Action:    cleanupv1alpha1.ActionDelete,
+						Phase:     "second",
+					},
+				},
+			}
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+
+			done, err := cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+			Expect(status.Phase).To(Equal("first"))
+			Expect(status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateWaiting))
+			Expect(status.Items[0].Message).To(ContainSubstring("Waiting for 1 object(s)"))
+			Expect(status.Items[1].State).To(Equal(cleanupv1alpha1.ItemStatePending))
+
+			// Verify the deployment was not deleted yet
+			d := &appsv1.Deployment{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(deployment), d)).To(Succeed())
+
+			// Release the pod and continue
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+			pod.SetFinalizers(nil)
+			Expect(c.Update(ctx, pod)).To(Succeed())
+
+			done, err = cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(status.Phase).To(Equal("second"))
+			Expect(status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateCompleted))
+			Expect(status.Items[1].State).To(Equal(cleanupv1alpha1.ItemStateCompleted))
+
+			err = c.Get(ctx, client.ObjectKeyFromObject(deployment), d)
+			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should stop before the next phase when an item fails", func() {
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{
+				MaxRetries: testEnv.Int32Ptr(0),
+				Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+					{
+						Kind:      "Deployment",
+						Namespace: ns.GetName(),
+						Name:      "missing-deployment",
+						Action:    cleanupv1alpha1.ActionDelete,
+						Phase:     "first",
+					},
+					{
+						Kind:      "StatefulSet",
+						Namespace: ns.GetName(),
+						Name:      statefulSet.GetName(),
+						Action:    cleanupv1alpha1.ActionDelete,
+						Phase:     "second",
+					},
+				},
+			}
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+
+			done, err := cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).To(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateFailed))
+			Expect(status.Items[1].State).To(Equal(cleanupv1alpha1.ItemStatePending))
+
+			// Verify the statefulset was not deleted
+			s := &appsv1.StatefulSet{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(statefulSet), s)).To(Succeed())
+		})
+
+		It("should retry only the failed items after a backoff", func() {
+			late := testEnv.WithRandomSuffix().Deployment("late-deployment", ns.GetName())
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{
+				MaxRetries: testEnv.Int32Ptr(2),
+				Backoff:    &metav1.Duration{Duration: time.Millisecond},
+				Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+					{
+						Kind:      "StatefulSet",
+						Namespace: ns.GetName(),
+						Name:      statefulSet.GetName(),
+						Action:    cleanupv1alpha1.ActionDelete,
+					},
+					{
+						Kind:      "Deployment",
+						Namespace: ns.GetName(),
+						Name:      late.GetName(),
+						Action:    cleanupv1alpha1.ActionDelete,
+					},
+				},
+			}
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+
+			done, err := cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+			Expect(status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateCompleted))
+			Expect(status.Items[1].State).To(Equal(cleanupv1alpha1.ItemStatePending))
+			Expect(status.Items[1].Attempts).To(Equal(int32(1)))
+			Expect(status.Items[1].NextRetryTime).NotTo(BeNil())
+
+			By("retrying once the failure is resolved")
+			Expect(c.Create(ctx, late)).To(Succeed())
+			time.Sleep(10 * time.Millisecond)
+
+			done, err = cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(status.Items[0].Attempts).To(Equal(int32(1)))
+			Expect(status.Items[1].State).To(Equal(cleanupv1alpha1.ItemStateCompleted))
+			Expect(status.Items[1].Attempts).To(Equal(int32(2)))
+		})
+
+		It("should fail an item once its retries run out", func() {
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{
+				MaxRetries: testEnv.Int32Ptr(1),
+				Backoff:    &metav1.Duration{Duration: time.Millisecond},
+				Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+					{
+						Kind:      "Deployment",
+						Namespace: ns.GetName(),
+						Name:      "missing-deployment",
+						Action:    cleanupv1alpha1.ActionDelete,
+					},
+				},
+			}
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+
+			done, err := cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+
+			time.Sleep(10 * time.Millisecond)
+			done, err = cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).To(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateFailed))
+			Expect(status.Items[0].Attempts).To(Equal(int32(2)))
+		})
+
+		It("should wait for deletion in the last phase when waitForDeletion is set", func() {
+			pod := testEnv.WithRandomSuffix().Pod("blocked-pod", ns.GetName())
+			pod.SetFinalizers([]string{"cleanup.quartz.metrostar.com/test"})
+			Expect(c.Create(ctx, pod)).To(Succeed())
+
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{
+				Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+					{
+						Kind:            "Pod",
+						Namespace:       ns.GetName(),
+						Name:            pod.GetName(),
+						Action:          cleanupv1alpha1.ActionDelete,
+						WaitForDeletion: true,
+					},
+				},
+			}
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+
+			done, err := cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+			Expect(status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateWaiting))
+			Expect(status.Items[0].StartTime).NotTo(BeNil())
+			Expect(status.Items[0].Objects).To(HaveLen(1))
+			Expect(status.Items[0].Objects[0].Message).To(ContainSubstring("cleanup.quartz.metrostar.com/test"))
+
+			// Release the pod and check again
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+			pod.SetFinalizers(nil)
+			Expect(c.Update(ctx, pod)).To(Succeed())
+
+			done, err = cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateCompleted))
+			Expect(status.Items[0].Objects[0].Result).To(Equal(cleanupv1alpha1.ResultDeleted))
+			Expect(status.Items[0].Objects[0].Message).To(BeEmpty())
+		})
+
+		It("should fail an item that does not converge within its timeout", func() {
+			pod := testEnv.WithRandomSuffix().Pod("blocked-pod", ns.GetName())
+			pod.SetFinalizers([]string{"cleanup.quartz.metrostar.com/test"})
+			Expect(c.Create(ctx, pod)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(c.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+				pod.SetFinalizers(nil)
+				Expect(c.Update(ctx, pod)).To(Succeed())
+			})
+
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{
+				Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+					{
+						Kind:            "Pod",
+						Namespace:       ns.GetName(),
+						Name:            pod.GetName(),
+						Action:          cleanupv1alpha1.ActionDelete,
+						WaitForDeletion: true,
+						Timeout:         &metav1.Duration{Duration: time.Minute},
+					},
+				},
+			}
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+
+			done, err := cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+
+			// Pretend the item started before its timeout
+			started := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+			status.Items[0].StartTime = &started
+
+			done, err = cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("timed out"))
+			Expect(done).To(BeTrue())
+			Expect(status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateFailed))
+			Expect(status.Items[0].Objects[0].Result).To(Equal(cleanupv1alpha1.ResultFailed))
+			Expect(status.Items[0].Objects[0].Message).To(ContainSubstring("timed out"))
+		})
+
+		It("should remove finalizers from objects still terminating after forceAfter", func() {
+			pod := testEnv.WithRandomSuffix().Pod("stuck-pod", ns.GetName())
+			pod.SetFinalizers([]string{"cleanup.quartz.metrostar.com/test"})
+			Expect(c.Create(ctx, pod)).To(Succeed())
+
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{
+				Resources: []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+					{
+						Kind:       "Pod",
+						Namespace:  ns.GetName(),
+						Name:       pod.GetName(),
+						Action:     cleanupv1alpha1.ActionDelete,
+						ForceAfter: &metav1.Duration{Duration: time.Minute},
+					},
+				},
+			}
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+
+			done, err := cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+			Expect(status.Items[0].Objects[0].Result).To(Equal(cleanupv1alpha1.ResultDeleted))
+
+			// Pretend the item started before the force deadline
+			started := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+			status.Items[0].StartTime = &started
+
+			_, err = cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.Items[0].Objects[0].Result).To(Equal(cleanupv1alpha1.ResultForced))
+			Expect(status.Items[0].Objects[0].RemovedFinalizers).To(ConsistOf("cleanup.quartz.metrostar.com/test"))
+
+			// Verify the pod is gone
+			Eventually(func() bool {
+				err := c.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{})
+				return client.IgnoreNotFound(err) == nil && err != nil
+			}).Should(BeTrue())
+
+			done, err = cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateCompleted))
+			Expect(status.Items[0].Objects[0].Result).To(Equal(cleanupv1alpha1.ResultForced))
+		})
+	})
+
+	Describe("RetryBackoff", func() {
+		It("should double the backoff for each attempt up to the maximum", func() {
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{}
+			Expect(RetryBackoff(spec, 1)).To(Equal(DefaultBackoff))
+			Expect(RetryBackoff(spec, 3)).To(Equal(4 * DefaultBackoff))
+			Expect(RetryBackoff(spec, 100)).To(Equal(MaxBackoff))
+
+			spec.Backoff = &metav1.Duration{Duration: time.Second}
+			Expect(RetryBackoff(spec, 2)).To(Equal(2 * time.Second))
+		})
+	})
+
+	Describe("MaxRetries", func() {
+		It("should retry failed items a few times unless the spec says otherwise", func() {
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{}
+			Expect(MaxRetries(spec)).To(Equal(int32(DefaultMaxRetries)))
+
+			spec.MaxRetries = testEnv.Int32Ptr(0)
+			Expect(MaxRetries(spec)).To(BeZero())
+		})
+	})
+
+	Describe("VerifyPlan", func() {
+		It("should proceed only while the plan matches the approved hash", func() {
+			items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				{
+					Kind:      "Deployment",
+					Namespace: ns.GetName(),
+					Action:    cleanupv1alpha1.ActionDelete,
+				},
+			}
+
+			planned, err := runItems(ctx, cleanupService, true, items)
+			Expect(err).NotTo(HaveOccurred())
+			hash := PlanHash(planned)
+
+			By("proceeding without an approved hash")
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+			Expect(cleanupService.VerifyPlan(ctx, cleanupv1alpha1.PreClusterDestroyCleanupSpec{Resources: items}, status)).To(BeTrue())
+
+			By("proceeding with a matching approved hash")
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{Resources: items, ApprovedPlanHash: hash}
+			Expect(cleanupService.VerifyPlan(ctx, spec, status)).To(BeTrue())
+			Expect(status.PlanHash).To(Equal(hash))
+			Expect(status.Items[0].ApprovedUIDs).To(ConsistOf(planned[0].Objects[0].UID))
+
+			By("flagging drift once the cluster changed")
+			extra := testEnv.WithRandomSuffix().Deployment("extra-deployment", ns.GetName())
+			Expect(c.Create(ctx, extra)).To(Succeed())
+
+			status = &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+			Expect(cleanupService.VerifyPlan(ctx, spec, status)).To(BeFalse())
+			Expect(status.PlanHash).NotTo(Equal(hash))
+			Expect(status.Items).To(HaveLen(1))
+			Expect(status.Items[0].Objects).To(HaveLen(2))
+
+			// Verify nothing was deleted
+			d := &appsv1.Deployment{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(deployment), d)).To(Succeed())
+		})
+		It("should only act on the objects of the approved plan once the run started", func() {
+			pod := testEnv.WithRandomSuffix().Pod("blocked-pod", ns.GetName())
+			pod.SetFinalizers([]string{"cleanup.quartz.metrostar.com/test"})
+			Expect(c.Create(ctx, pod)).To(Succeed())
+
+			items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				{Kind: "Pod", Namespace: ns.GetName(), Name: pod.GetName(), Action: cleanupv1alpha1.ActionDelete, Phase: "pods"},
+				{Kind: "Deployment", Namespace: ns.GetName(), Action: cleanupv1alpha1.ActionScaleToZero, Phase: "apps"},
+			}
+
+			planned := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+			done, err := cleanupService.CleanupPhases(ctx, cleanupv1alpha1.PreClusterDestroyCleanupSpec{DryRun: true, Resources: items}, planned)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{Resources: items, ApprovedPlanHash: PlanHash(planned.Items)}
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+			Expect(cleanupService.VerifyPlan(ctx, spec, status)).To(BeTrue())
+
+			By("waiting for the pod of the first phase to be deleted")
+			done, err = cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+
+			By("creating a deployment after the plan was approved")
+			extra := testEnv.WithRandomSuffix().Deployment("extra-deployment", ns.GetName())
+			Expect(c.Create(ctx, extra)).To(Succeed())
+			Expect(cleanupService.VerifyPlan(ctx, spec, status)).To(BeTrue())
+
+			// Release the pod and continue with the next phase
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+			pod.SetFinalizers(nil)
+			Expect(c.Update(ctx, pod)).To(Succeed())
+
+			done, err = cleanupService.CleanupPhases(ctx, spec, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+
+			results := map[string]string{}
+			for _, o := range status.Items[1].Objects {
+				results[o.Name] = o.Result
+			}
+			Expect(results).To(HaveKeyWithValue(deployment.GetName(), cleanupv1alpha1.ResultScaled))
+			Expect(results).To(HaveKeyWithValue(extra.GetName(), cleanupv1alpha1.ResultSkipped))
+
+			d := &appsv1.Deployment{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(extra), d)).To(Succeed())
+			Expect(*d.Spec.Replicas).To(Equal(*extra.Spec.Replicas))
+		})
+
+		It("should plan the phases after a failed phase", func() {
+			items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				{Kind: "NotAKind", Namespace: ns.GetName(), Action: cleanupv1alpha1.ActionDelete, Phase: "first"},
+				{Kind: "Deployment", Namespace: ns.GetName(), Action: cleanupv1alpha1.ActionDelete, Phase: "second"},
+			}
+
+			planned := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+			spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{DryRun: true, Resources: items, MaxRetries: testEnv.Int32Ptr(0)}
+			done, err := cleanupService.CleanupPhases(ctx, spec, planned)
+			Expect(err).To(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(planned.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateFailed))
+			Expect(planned.Items[1].State).To(Equal(cleanupv1alpha1.ItemStateCompleted))
+			Expect(planned.Items[1].Objects).To(HaveLen(1))
+
+			spec = cleanupv1alpha1.PreClusterDestroyCleanupSpec{Resources: items, ApprovedPlanHash: PlanHash(planned.Items)}
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+			Expect(cleanupService.VerifyPlan(ctx, spec, status)).To(BeTrue())
+			Expect(status.Items[1].ApprovedUIDs).To(ConsistOf(planned.Items[1].Objects[0].UID))
+		})
+	})
+
+	Describe("Revert", func() {
+		It("should restore the workloads scaled by the items", func() {
+			items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				{
+					Kind:      "Deployment",
+					Namespace: ns.GetName(),
+					Action:    cleanupv1alpha1.ActionScaleToZero,
+				},
+				{
+					Kind:      "StatefulSet",
+					Namespace: ns.GetName(),
+					Action:    cleanupv1alpha1.ActionScaleToZero,
+				},
+			}
+
+			statuses, err := runItems(ctx, cleanupService, false, items)
+			Expect(err).NotTo(HaveOccurred())
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{Items: statuses}
+
+			By("reverting in dry run mode")
+			count, err := cleanupService.Revert(ctx, true, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(2))
+			Expect(CountResults(status.Items, cleanupv1alpha1.ResultScaled)).To(Equal(2))
+
+			By("reverting")
+			count, err = cleanupService.Revert(ctx, false, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(2))
+			Expect(CountResults(status.Items, cleanupv1alpha1.ResultRestored)).To(Equal(2))
+
+			d := &appsv1.Deployment{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(deployment), d)).To(Succeed())
+			Expect(*d.Spec.Replicas).To(Equal(*deployment.Spec.Replicas))
+
+			s := &appsv1.StatefulSet{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(statefulSet), s)).To(Succeed())
+			Expect(*s.Spec.Replicas).To(Equal(*statefulSet.Spec.Replicas))
+		})
+
+		It("should restore workloads whose scale timed out, from their annotation", func() {
+			items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				{
+					Kind:      "Deployment",
+					Namespace: ns.GetName(),
+					Name:      deployment.GetName(),
+					Action:    cleanupv1alpha1.ActionScaleToZero,
+				},
+			}
+
+			statuses, err := runItems(ctx, cleanupService, false, items)
+			Expect(err).NotTo(HaveOccurred())
+
+			// the deployment was scaled, but its replicas did not terminate in time
+			statuses[0].State = cleanupv1alpha1.ItemStateFailed
+			statuses[0].Objects[0].Result = cleanupv1alpha1.ResultFailed
+			status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{Items: statuses}
+
+			count, err := cleanupService.Revert(ctx, false, status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(1))
+			Expect(status.Items[0].Objects[0].Result).To(Equal(cleanupv1alpha1.ResultRestored))
+
+			d := &appsv1.Deployment{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(deployment), d)).To(Succeed())
+			Expect(*d.Spec.Replicas).To(Equal(*deployment.Spec.Replicas))
+		})
+	})
+
+	Describe("mergeObjects", func() {
+		It("should keep the objects of earlier attempts that the latest attempt did not report", func() {
+			earlier := []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{
+				{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "apps", Name: "web", Result: cleanupv1alpha1.ResultScaled},
+				{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "apps", Name: "api", Result: cleanupv1alpha1.ResultFailed},
+			}
+			latest := []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{
+				{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "apps", Name: "api", Result: cleanupv1alpha1.ResultScaled},
+			}
+
+			merged := mergeObjects(earlier, latest)
+			Expect(merged).To(HaveLen(2))
+			Expect(merged[0].Name).To(Equal("api"))
+			Expect(merged[0].Result).To(Equal(cleanupv1alpha1.ResultScaled))
+			Expect(merged[1].Name).To(Equal("web"))
+			Expect(merged[1].Result).To(Equal(cleanupv1alpha1.ResultScaled))
+		})
+	})
 })
+
+// runItems runs items as the single phase of a cleanup with CleanupPhases, and returns the status of each item.
+func runItems(ctx context.Context, s *CleanupService, dryRun bool, items []cleanupv1alpha1.PreClusterDestroyCleanupItem) ([]cleanupv1alpha1.PreClusterDestroyCleanupItemStatus, error) {
+	status := &cleanupv1alpha1.PreClusterDestroyCleanupStatus{}
+	spec := cleanupv1alpha1.PreClusterDestroyCleanupSpec{DryRun: dryRun, Resources: items, MaxRetries: testEnv.Int32Ptr(0)}
+	_, err := s.CleanupPhases(ctx, spec, status)
+	return status.Items, err
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
+	"go.opentelemetry.io/otel/attribute"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -18,123 +22,168 @@ import (
 type DeleteService struct {
 	client client.Client
 	lookup *LookupService
+	deny   DenyList
 	logger logr.Logger
 }
 
 // NewDeleteService creates a new DeleteService instance.
-func NewDeleteService(ctx context.Context, client client.Client, lookup *LookupService) *DeleteService {
+func NewDeleteService(ctx context.Context, client client.Client, lookup *LookupService, deny DenyList) *DeleteService {
 	return &DeleteService{
 		client: client,
 		lookup: lookup,
+		deny:   deny,
 		logger: log.FromContext(ctx),
 	}
 }
 
-func (s *DeleteService) DeleteItem(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, item cleanupv1alpha1.PreClusterDestroyCleanupItem) (int, error) {
-	// special case to handle deletion of all resources of crds with a specific category, ex. "kubectl get managed"
-	if gvk.Kind == CustomResourceDefinitionKind && item.Category != "" {
-		gvks, err := s.lookup.LookupCrdsByCategory(ctx, item.Category)
-		if err != nil {
-			return 0, fmt.Errorf("failed to lookup CRDs by category %s: %w", item.Category, err)
-		}
+// DeleteItem deletes the objects of the target of a PreClusterDestroyCleanupItem, see LookupTarget.
+// It returns the status of each object touched and any errors encountered during deletion.
+func (s *DeleteService) DeleteItem(ctx context.Context, dryRun bool, target Target, item cleanupv1alpha1.PreClusterDestroyCleanupItem) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	if len(target.Kinds) == 0 {
+		s.logger.Info("No kinds found for category", "category", item.Category)
+		return nil, nil // Nothing to delete
+	}
 
-		if len(gvks) == 0 {
-			s.logger.Info("No CRDs found for category", "category", item.Category)
-			return 0, nil // Nothing to delete
-		}
+	return s.lookup.ForEachObject(ctx, target, item, s.deny, s.deleteFunc(dryRun))
+}
 
-		count := 0
-		errs := []error{}
-		for _, gvk := range gvks {
-			c, err := s.DeleteResources(ctx, dryRun, gvk, item.Namespace)
-			if err != nil {
-				errs = append(errs, fmt.Errorf("failed to cleanup resources of kind %s in namespace %s: %w", gvk.Kind, item.Namespace, err))
-				continue
-			}
-			count += c
-		}
-		return count, errors.Join(errs...)
-	}
+// DeleteResources deletes all resources of a specific kind in a given namespace, optionally narrowed by list options.
+// It returns the status of each object found and any errors encountered during deletion.
+// If dryRun is true, the deletions are sent with DryRunAll so the API server validates them without deleting anything.
+func (s *DeleteService) DeleteResources(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, ns string, opts ...client.ListOption) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	return s.lookup.ForEachObjectIn(ctx, gvk, ns, "", &Protection{deny: s.deny}, s.deleteFunc(dryRun), opts...)
+}
+
+// DeleteNamedResource deletes a specific resource by its kind, namespace, and name.
+// It returns the status of the object and any errors encountered during deletion.
+// If dryRun is true, the deletion is sent with DryRunAll so the API server validates it without deleting the resource.
+func (s *DeleteService) DeleteNamedResource(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, ns string, name string) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	results, err := s.lookup.ForEachObjectIn(ctx, gvk, ns, name, &Protection{deny: s.deny}, s.deleteFunc(dryRun))
+	return results[0], err
+}
 
-	// if no name was specified, delete all resources of the specified kind, optionally scoped to a namespace
-	if item.Name == "" {
-		c, err := s.DeleteResources(ctx, dryRun, gvk, item.Namespace)
-		if err != nil {
-			return c, fmt.Errorf("failed to cleanup resources of kind %s in namespace %s: %w", item.Kind, item.Namespace, err)
+// deleteFunc returns the ObjectFunc deleting each object matched by an item.
+func (s *DeleteService) deleteFunc(dryRun bool) ObjectFunc {
+	return func(ctx context.Context, gvk schema.GroupVersionKind, obj *metav1.PartialObjectMetadata) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+		if err := s.deleteObject(ctx, dryRun, gvk, obj); err != nil {
+			err = fmt.Errorf("failed to delete %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+			return NewObjectStatus(gvk, obj, cleanupv1alpha1.ResultFailed, err), err
 		}
-		return c, nil
+		return ObjectStatus(dryRun, gvk, obj, cleanupv1alpha1.ResultDeleted, nil), nil
 	}
+}
 
-	// if a name was specified, delete the specific resource
-	c, err := s.DeleteNamedResource(ctx, dryRun, gvk, item.Namespace, item.Name)
-	if err != nil {
-		return c, fmt.Errorf("failed to cleanup named resource %s/%s: %w", item.Namespace, item.Name, err)
-	}
-	return c, nil
+// deleteObject deletes a single object, in a span of its own.
+func (s *DeleteService) deleteObject(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, item *metav1.PartialObjectMetadata) error {
+	ctx, span := StartSpan(ctx, "DeleteObject", objectAttributes(dryRun, gvk, item.GetNamespace(), item.GetName())...)
+	s.logger.Info("Deleting item", "kind", gvk.Kind, "namespace", item.GetNamespace(), "name", item.GetName(), "dryRun", dryRun)
+	err := s.client.Delete(ctx, item, deleteOptions(dryRun)...)
+	EndSpan(span, err)
+	return err
 }
 
-// DeleteResources deletes all resources of a specific kind in a given namespace.
-// It returns the count of deleted resources and any errors encountered during deletion.
-// If dryRun is true, it only logs the resources that would be deleted without actually deleting them.
-func (s *DeleteService) DeleteResources(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, ns string) (int, error) {
-	list, err := s.lookup.ListResources(ctx, gvk, ns)
-	if err != nil {
-		return 0, fmt.Errorf("failed to list resources of kind %s in namespace %s: %w", gvk.Kind, ns, err)
+// deleteOptions returns the options for a delete request, sending it with DryRunAll if dryRun is true.
+func deleteOptions(dryRun bool) []client.DeleteOption {
+	if dryRun {
+		return []client.DeleteOption{client.DryRunAll}
 	}
+	return nil
+}
 
-	if len(list.Items) == 0 {
-		s.logger.Info("No resources found to delete", "kind", gvk.Kind, "namespace", ns)
-		return 0, nil // Nothing to delete
-	}
+// PendingDeletion returns the deleted objects that still exist in the cluster, e.g. because finalizers
+// have not yet released them. Objects that were recreated with a different UID are considered deleted.
+func (s *DeleteService) PendingDeletion(ctx context.Context, objects []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	ctx, span := StartSpan(ctx, "PendingDeletion", attribute.Int("quartz.objects", len(objects)))
+	pending, err := s.pendingDeletion(ctx, objects)
+	span.SetAttributes(attribute.Int("quartz.pending", len(pending)))
+	EndSpan(span, err)
+	return pending, err
+}
 
-	if dryRun {
-		s.logger.Info("Dry run mode, skipping deletion", "kind", gvk.Kind, "namespace", ns, "count", len(list.Items))
-		for _, item := range list.Items {
-			s.logger.Info("Would delete item", "kind", gvk.Kind, "namespace", item.GetNamespace(), "name", item.GetName())
+// pendingDeletion returns the deleted objects that still exist in the cluster.
+func (s *DeleteService) pendingDeletion(ctx context.Context, objects []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	pending := []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{}
+	errs := []error{}
+	for _, o := range objects {
+		if o.Result != cleanupv1alpha1.ResultDeleted && o.Result != cleanupv1alpha1.ResultForced {
+			continue
 		}
-		return len(list.Items), nil
-	}
 
-	count := 0
-	errs := []error{}
-	for _, item := range list.Items {
-		s.logger.Info("Deleting item", "kind", gvk.Kind, "namespace", item.GetNamespace(), "name", item.GetName())
-		if err := s.client.Delete(ctx, &item); err != nil {
-			errs = append(errs, fmt.Errorf("failed to delete %s/%s: %w", item.GetNamespace(), item.GetName(), err))
+		item := &metav1.PartialObjectMetadata{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       o.Kind,
+				APIVersion: o.APIVersion,
+			},
+		}
+		if err := s.client.Get(ctx, client.ObjectKey{Namespace: o.Namespace, Name: o.Name}, item); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("failed to get %s/%s: %w", o.Namespace, o.Name, err))
+			continue
+		}
+
+		if o.UID != "" && item.GetUID() != o.UID {
 			continue
 		}
-		count++
+
+		o.Message = "Waiting for deletion"
+		if len(item.GetFinalizers()) > 0 {
+			o.Message = fmt.Sprintf("Waiting for finalizers: %s", strings.Join(item.GetFinalizers(), ", "))
+		}
+		pending = append(pending, o)
 	}
 
-	return count, errors.Join(errs...)
+	return pending, errors.Join(errs...)
 }
 
-// DeleteNamedResource deletes a specific resource by its kind, namespace, and name.
-// It returns the count of deleted resources (1 if successful, 0 if not found) and any errors encountered during deletion.
-// If dryRun is true, it only logs the resource that would be deleted without actually deleting it.
-func (s *DeleteService) DeleteNamedResource(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, ns string, name string) (int, error) {
-	item := &metav1.PartialObjectMetadata{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       gvk.Kind,
-			APIVersion: gvk.GroupVersion().String(),
-		},
-	}
+// RemoveFinalizers clears the finalizers of deleted objects that are still terminating, forcing their removal.
+// It returns the status of each object whose finalizers were removed, recording the finalizers for auditing.
+// Objects that are gone, were recreated, or are not being deleted are left untouched.
+func (s *DeleteService) RemoveFinalizers(ctx context.Context, objects []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	forced := []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{}
+	errs := []error{}
+	for _, o := range objects {
+		item := &metav1.PartialObjectMetadata{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       o.Kind,
+				APIVersion: o.APIVersion,
+			},
+		}
+		if err := s.client.Get(ctx, client.ObjectKey{Namespace: o.Namespace, Name: o.Name}, item); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("failed to get %s/%s: %w", o.Namespace, o.Name, err))
+			continue
+		}
 
-	if err := s.client.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, item); err != nil {
-		return 0, fmt.Errorf("failed to get %s/%s: %w", ns, name, err)
-	}
+		if (o.UID != "" && item.GetUID() != o.UID) || item.GetDeletionTimestamp() == nil || len(item.GetFinalizers()) == 0 {
+			continue
+		}
 
-	if dryRun {
-		logger := log.FromContext(ctx)
-		logger.Info("Dry run mode, skipping deletion", "kind", gvk.Kind, "namespace", ns, "name", name)
-		logger.Info("Would delete item", "kind", gvk.Kind, "namespace", item.GetNamespace(), "name", item.GetName())
-		return 1, nil
-	}
+		finalizers := item.GetFinalizers()
+		if err := s.removeFinalizers(ctx, item); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove finalizers from %s/%s: %w", o.Namespace, o.Name, err))
+			continue
+		}
 
-	s.logger.Info("Deleting item", "kind", gvk.Kind, "namespace", item.GetNamespace(), "name", item.GetName())
-	if err := s.client.Delete(ctx, item); err != nil {
-		return 0, fmt.Errorf("failed to delete %s/%s: %w", item.GetNamespace(), item.GetName(), err)
+		o.Result = cleanupv1alpha1.ResultForced
+		o.Message = ""
+		o.RemovedFinalizers = finalizers
+		forced = append(forced, o)
 	}
 
-	return 1, nil
+	return forced, errors.Join(errs...)
+}
+
+// removeFinalizers removes the finalizers of a single terminating object, in a span of its own.
+func (s *DeleteService) removeFinalizers(ctx context.Context, item *metav1.PartialObjectMetadata) error {
+	gvk := item.GroupVersionKind()
+	ctx, span := StartSpan(ctx, "RemoveFinalizers", append(objectAttributes(false, gvk, item.GetNamespace(), item.GetName()), attribute.StringSlice("quartz.finalizers", item.GetFinalizers()))...)
+	s.logger.Info("Removing finalizers from terminating item", "kind", gvk.Kind, "namespace", item.GetNamespace(), "name", item.GetName(), "finalizers", item.GetFinalizers())
+	patch := client.RawPatch(types.MergePatchType, []byte(`{"metadata":{"finalizers":null}}`))
+	err := s.client.Patch(ctx, item, patch)
+	EndSpan(span, err)
+	return err
 }
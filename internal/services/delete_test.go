@@ -40,8 +40,8 @@ var _ = Describe("DeleteService", func() {
 		Expect(c.Create(ctx, pod2)).To(Succeed())
 
 		// Initialize services
-		lookupService = NewLookupService(ctx, c, t.Cfg)
-		deleteService = NewDeleteService(ctx, c, lookupService)
+		lookupService = NewLookupService(ctx, c, t.Cfg, Options{Reader: c})
+		deleteService = NewDeleteService(ctx, c, lookupService, DenyList{})
 	})
 
 	Describe("DeleteNamedResource", func() {
@@ -53,9 +53,12 @@ var _ = Describe("DeleteService", func() {
 				Kind:    "Pod",
 			}
 
-			count, err := deleteService.DeleteNamedResource(ctx, false, gvk, ns.GetName(), pod1.GetName())
+			result, err := deleteService.DeleteNamedResource(ctx, false, gvk, ns.GetName(), pod1.GetName())
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(1))
+			Expect(result.Result).To(Equal(cleanupv1alpha1.ResultDeleted))
+			Expect(result.Name).To(Equal(pod1.GetName()))
+			Expect(result.Namespace).To(Equal(ns.GetName()))
+			Expect(result.UID).To(Equal(pod1.GetUID()))
 
 			// Verify the resource was deleted
 			err = c.Get(ctx, types.NamespacedName{Namespace: ns.GetName(), Name: pod1.GetName()},
@@ -76,9 +79,10 @@ var _ = Describe("DeleteService", func() {
 				Kind:    "Pod",
 			}
 
-			count, err := deleteService.DeleteNamedResource(ctx, true, gvk, ns.GetName(), pod1.GetName())
+			result, err := deleteService.DeleteNamedResource(ctx, true, gvk, ns.GetName(), pod1.GetName())
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(1))
+			Expect(result.Result).To(Equal(cleanupv1alpha1.ResultDeleted))
+			Expect(result.Message).To(Equal(DryRunMessage))
 
 			// Verify the resource still exists
 			err = c.Get(ctx, types.NamespacedName{Namespace: ns.GetName(), Name: pod1.GetName()},
@@ -93,8 +97,11 @@ var _ = Describe("DeleteService", func() {
 				Kind:    "Pod",
 			}
 
-			_, err := deleteService.DeleteNamedResource(ctx, false, gvk, ns.GetName(), "non-existent-pod")
+			result, err := deleteService.DeleteNamedResource(ctx, false, gvk, ns.GetName(), "non-existent-pod")
 			Expect(err).To(HaveOccurred())
+			Expect(result.Result).To(Equal(cleanupv1alpha1.ResultFailed))
+			Expect(result.Name).To(Equal("non-existent-pod"))
+			Expect(result.Message).NotTo(BeEmpty())
 		})
 	})
 
@@ -106,9 +113,14 @@ var _ = Describe("DeleteService", func() {
 				Kind:    "Pod",
 			}
 
-			count, err := deleteService.DeleteResources(ctx, false, gvk, ns.GetName())
+			results, err := deleteService.DeleteResources(ctx, false, gvk, ns.GetName())
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(2))
+			Expect(results).To(HaveLen(2))
+			for _, r := range results {
+				Expect(r.Result).To(Equal(cleanupv1alpha1.ResultDeleted))
+				Expect(r.Kind).To(Equal("Pod"))
+				Expect(r.APIVersion).To(Equal("v1"))
+			}
 
 			// Verify all resources were deleted
 			list := &metav1.PartialObjectMetadataList{}
@@ -130,9 +142,12 @@ var _ = Describe("DeleteService", func() {
 				Kind:    "Pod",
 			}
 
-			count, err := deleteService.DeleteResources(ctx, true, gvk, ns.GetName())
+			results, err := deleteService.DeleteResources(ctx, true, gvk, ns.GetName())
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(2))
+			Expect(results).To(HaveLen(2))
+			for _, r := range results {
+				Expect(r.Message).To(Equal(DryRunMessage))
+			}
 
 			// Verify resources still exist
 			list := &metav1.PartialObjectMetadataList{}
@@ -157,9 +172,9 @@ var _ = Describe("DeleteService", func() {
 				Action:    cleanupv1alpha1.ActionDelete,
 			}
 
-			count, err := deleteService.DeleteItem(ctx, false, schema.GroupVersionKind{Kind: "Pod", Version: "v1"}, item)
+			results, err := deleteService.DeleteItem(ctx, false, lookupTarget(ctx, schema.GroupVersionKind{Kind: "Pod", Version: "v1"}, item), item)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(1))
+			Expect(results).To(HaveLen(1))
 
 			// Verify the resource was deleted
 			err = c.Get(ctx, types.NamespacedName{Namespace: ns.GetName(), Name: pod1.GetName()},
@@ -175,9 +190,9 @@ var _ = Describe("DeleteService", func() {
 				Action:    cleanupv1alpha1.ActionDelete,
 			}
 
-			count, err := deleteService.DeleteItem(ctx, false, schema.GroupVersionKind{Kind: "Pod", Version: "v1"}, item)
+			results, err := deleteService.DeleteItem(ctx, false, lookupTarget(ctx, schema.GroupVersionKind{Kind: "Pod", Version: "v1"}, item), item)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(2))
+			Expect(results).To(HaveLen(2))
 
 			// Verify all resources were deleted
 			list := &metav1.PartialObjectMetadataList{}
@@ -191,5 +206,280 @@ var _ = Describe("DeleteService", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(list.Items).To(BeEmpty())
 		})
+
+		It("should delete the items of every page when the page size is smaller than the results", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      "Pod",
+				Namespace: ns.GetName(),
+				Action:    cleanupv1alpha1.ActionDelete,
+				PageSize:  1,
+			}
+
+			results, err := deleteService.DeleteItem(ctx, false, lookupTarget(ctx, schema.GroupVersionKind{Kind: "Pod", Version: "v1"}, item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+
+			list := &metav1.PartialObjectMetadataList{}
+			list.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "PodList"})
+			Expect(c.List(ctx, list, client.InNamespace(ns.GetName()))).To(Succeed())
+			Expect(list.Items).To(BeEmpty())
+		})
+
+		It("should page through every item with a cache-backed client and an API reader", func() {
+			cacheCtx, cancel := context.WithCancel(ctx)
+			DeferCleanup(cancel)
+			cached := testEnv.CachedClient(cacheCtx)
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      "Pod",
+				Namespace: ns.GetName(),
+				Action:    cleanupv1alpha1.ActionDelete,
+				PageSize:  1,
+			}
+			gvk := schema.GroupVersionKind{Kind: "Pod", Version: "v1"}
+
+			By("refusing to list through the cache alone, which would only return the first page")
+			opts, err := SelectorListOptions(item)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = NewLookupService(ctx, cached, testEnv.Cfg, Options{}).ListResources(ctx, gvk, ns.GetName(), opts...)
+			Expect(err).To(MatchError(ContainSubstring("requires a reader of the API server")))
+
+			By("listing every page through the API reader")
+			status, err := NewCleanupService(ctx, cached, testEnv.Cfg, Options{Reader: c}).CleanupItem(ctx, false, item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.Objects).To(HaveLen(2))
+
+			list := &metav1.PartialObjectMetadataList{}
+			list.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "PodList"})
+			Expect(c.List(ctx, list, client.InNamespace(ns.GetName()))).To(Succeed())
+			Expect(list.Items).To(BeEmpty())
+		})
+	})
+
+	Describe("DeleteItem with selectors", func() {
+		It("should only delete items matching the label selector", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      "Pod",
+				Namespace: ns.GetName(),
+				Action:    cleanupv1alpha1.ActionDelete,
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": pod2.GetName()},
+				},
+			}
+
+			results, err := deleteService.DeleteItem(ctx, false, lookupTarget(ctx, schema.GroupVersionKind{Kind: "Pod", Version: "v1"}, item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Name).To(Equal(pod2.GetName()))
+
+			// Verify the other resource still exists
+			err = c.Get(ctx, types.NamespacedName{Namespace: ns.GetName(), Name: pod1.GetName()},
+				&metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"}})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should only delete items matching the field selector", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:          "Pod",
+				Namespace:     ns.GetName(),
+				Action:        cleanupv1alpha1.ActionDelete,
+				FieldSelector: "metadata.name=" + pod1.GetName(),
+			}
+
+			results, err := deleteService.DeleteItem(ctx, false, lookupTarget(ctx, schema.GroupVersionKind{Kind: "Pod", Version: "v1"}, item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Name).To(Equal(pod1.GetName()))
+		})
+
+		It("should send field selectors to the API server with a cache-backed client and an API reader", func() {
+			cacheCtx, cancel := context.WithCancel(ctx)
+			DeferCleanup(cancel)
+			cached := testEnv.CachedClient(cacheCtx)
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:          "Pod",
+				Namespace:     ns.GetName(),
+				Action:        cleanupv1alpha1.ActionDelete,
+				FieldSelector: "metadata.name=" + pod1.GetName(),
+			}
+
+			By("failing through the cache alone, which has no reader of the API server")
+			_, err := NewCleanupService(ctx, cached, testEnv.Cfg, Options{}).CleanupItem(ctx, true, item)
+			Expect(err).To(HaveOccurred())
+
+			By("filtering on the API server through the API reader")
+			status, err := NewCleanupService(ctx, cached, testEnv.Cfg, Options{Reader: c}).CleanupItem(ctx, false, item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.Objects).To(HaveLen(1))
+			Expect(status.Objects[0].Name).To(Equal(pod1.GetName()))
+		})
+	})
+
+	Describe("DeleteItem with namespaces", func() {
+		It("should delete items in every listed namespace", func() {
+			t := testEnv.WithRandomSuffix()
+			ns2 := t.Namespace("deleteservice")
+			pod3 := t.Pod("test-pod-3", ns2.GetName())
+
+			Expect(c.Create(ctx, ns2)).To(Succeed())
+			Expect(c.Create(ctx, pod3)).To(Succeed())
+
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:       "Pod",
+				Namespaces: []string{ns.GetName(), ns2.GetName()},
+				Action:     cleanupv1alpha1.ActionDelete,
+			}
+
+			results, err := deleteService.DeleteItem(ctx, false, lookupTarget(ctx, schema.GroupVersionKind{Kind: "Pod", Version: "v1"}, item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(3))
+
+			names := []string{}
+			for _, r := range results {
+				names = append(names, r.Namespace+"/"+r.Name)
+			}
+			Expect(names).To(ConsistOf(
+				ns.GetName()+"/"+pod1.GetName(),
+				ns.GetName()+"/"+pod2.GetName(),
+				ns2.GetName()+"/"+pod3.GetName(),
+			))
+		})
+
+		It("should do nothing when the namespace selector matches no namespaces", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:              "Pod",
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"teardown": "does-not-exist"}},
+				Action:            cleanupv1alpha1.ActionDelete,
+			}
+
+			results, err := deleteService.DeleteItem(ctx, false, lookupTarget(ctx, schema.GroupVersionKind{Kind: "Pod", Version: "v1"}, item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(BeEmpty())
+
+			// Verify the resources still exist
+			err = c.Get(ctx, types.NamespacedName{Namespace: ns.GetName(), Name: pod1.GetName()},
+				&metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"}})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("DeleteItem with protection", func() {
+		It("should skip and report objects that are protected or excluded", func() {
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pod1), pod1)).To(Succeed())
+			pod1.SetAnnotations(map[string]string{cleanupv1alpha1.ProtectAnnotation: "true"})
+			Expect(c.Update(ctx, pod1)).To(Succeed())
+
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      "Pod",
+				Namespace: ns.GetName(),
+				Action:    cleanupv1alpha1.ActionDelete,
+				Exclude: &cleanupv1alpha1.PreClusterDestroyCleanupExclude{
+					Names: []string{pod2.GetName()},
+				},
+			}
+
+			results, err := deleteService.DeleteItem(ctx, false, lookupTarget(ctx, schema.GroupVersionKind{Kind: "Pod", Version: "v1"}, item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+			for _, r := range results {
+				Expect(r.Result).To(Equal(cleanupv1alpha1.ResultSkipped))
+				Expect(r.Message).NotTo(BeEmpty())
+			}
+
+			// Verify both resources still exist
+			for _, pod := range []*corev1.Pod{pod1, pod2} {
+				err = c.Get(ctx, client.ObjectKeyFromObject(pod),
+					&metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"}})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("should never delete objects in namespaces on the deny list", func() {
+			deleteService = NewDeleteService(ctx, c, lookupService, DenyList{Namespaces: []string{ns.GetName()}})
+			gvk := schema.GroupVersionKind{Kind: "Pod", Version: "v1"}
+
+			result, err := deleteService.DeleteNamedResource(ctx, false, gvk, ns.GetName(), pod1.GetName())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Result).To(Equal(cleanupv1alpha1.ResultSkipped))
+			Expect(result.Message).To(ContainSubstring("deny list"))
+
+			results, err := deleteService.DeleteResources(ctx, false, gvk, ns.GetName())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+			Expect(CountResults([]cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{{Objects: results}}, cleanupv1alpha1.ResultSkipped)).To(Equal(2))
+		})
+	})
+
+	Describe("PendingDeletion", func() {
+		It("should report deleted objects that are still held by finalizers", func() {
+			gvk := schema.GroupVersionKind{
+				Group:   "",
+				Version: "v1",
+				Kind:    "Pod",
+			}
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pod1), pod1)).To(Succeed())
+			pod1.SetFinalizers([]string{"cleanup.quartz.metrostar.com/test"})
+			Expect(c.Update(ctx, pod1)).To(Succeed())
+
+			results, err := deleteService.DeleteResources(ctx, false, gvk, ns.GetName())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+
+			pending, err := deleteService.PendingDeletion(ctx, results)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(HaveLen(1))
+			Expect(pending[0].Name).To(Equal(pod1.GetName()))
+			Expect(pending[0].Message).To(ContainSubstring("cleanup.quartz.metrostar.com/test"))
+
+			// Release the pod
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pod1), pod1)).To(Succeed())
+			pod1.SetFinalizers(nil)
+			Expect(c.Update(ctx, pod1)).To(Succeed())
+
+			pending, err = deleteService.PendingDeletion(ctx, results)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(BeEmpty())
+		})
+	})
+
+	Describe("RemoveFinalizers", func() {
+		It("should only remove finalizers from objects that are terminating", func() {
+			gvk := schema.GroupVersionKind{
+				Group:   "",
+				Version: "v1",
+				Kind:    "Pod",
+			}
+
+			for _, p := range []*corev1.Pod{pod1, pod2} {
+				Expect(c.Get(ctx, client.ObjectKeyFromObject(p), p)).To(Succeed())
+				p.SetFinalizers([]string{"cleanup.quartz.metrostar.com/test"})
+				Expect(c.Update(ctx, p)).To(Succeed())
+			}
+			DeferCleanup(func() {
+				Expect(c.Get(ctx, client.ObjectKeyFromObject(pod2), pod2)).To(Succeed())
+				pod2.SetFinalizers(nil)
+				Expect(c.Update(ctx, pod2)).To(Succeed())
+			})
+
+			deleted, err := deleteService.DeleteNamedResource(ctx, false, gvk, ns.GetName(), pod1.GetName())
+			Expect(err).NotTo(HaveOccurred())
+			untouched := NewObjectStatus(gvk, pod2, cleanupv1alpha1.ResultDeleted, nil)
+
+			forced, err := deleteService.RemoveFinalizers(ctx, []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{deleted, untouched})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(forced).To(HaveLen(1))
+			Expect(forced[0].Name).To(Equal(pod1.GetName()))
+			Expect(forced[0].Result).To(Equal(cleanupv1alpha1.ResultForced))
+			Expect(forced[0].RemovedFinalizers).To(ConsistOf("cleanup.quartz.metrostar.com/test"))
+
+			// Verify the terminating pod is gone and the other pod kept its finalizer
+			err = c.Get(ctx, client.ObjectKeyFromObject(pod1), &corev1.Pod{})
+			Expect(err).To(HaveOccurred())
+			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+
+			p := &corev1.Pod{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pod2), p)).To(Succeed())
+			Expect(p.GetFinalizers()).To(ConsistOf("cleanup.quartz.metrostar.com/test"))
+		})
 	})
 })
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+const (
+	EventReasonItemStarted   = "ItemStarted"
+	EventReasonItemCompleted = "ItemCompleted"
+	EventReasonItemRetrying  = "ItemRetrying"
+	EventReasonItemFailed    = "ItemFailed"
+	EventReasonCleanupFailed = "CleanupFailed"
+)
+
+// EventService provides methods to record Kubernetes Events about the progress of a PreClusterDestroyCleanup.
+type EventService struct {
+	recorder record.EventRecorder
+	targets  bool
+}
+
+// NewEventService creates a new EventService instance.
+// If targets is true, events are also recorded on each object acted on. A nil recorder records nothing.
+func NewEventService(recorder record.EventRecorder, targets bool) *EventService {
+	return &EventService{
+		recorder: recorder,
+		targets:  targets,
+	}
+}
+
+// Event records an event on a PreClusterDestroyCleanup object.
+func (s *EventService) Event(obj *cleanupv1alpha1.PreClusterDestroyCleanup, eventType string, reason string, message string) {
+	if s.recorder == nil {
+		return
+	}
+	s.recorder.Event(obj, eventType, reason, message)
+}
+
+// RecordProgress records the start, completion, retry and failure of the items of a PreClusterDestroyCleanup,
+// by comparing the status of its items with their status before they were processed.
+// Objects acted on by started items get an event too when targets are enabled, except in dry run mode.
+func (s *EventService) RecordProgress(obj *cleanupv1alpha1.PreClusterDestroyCleanup, before []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) {
+	if s.recorder == nil {
+		return
+	}
+
+	suffix := ""
+	if obj.Spec.DryRun {
+		suffix = " (dry run)"
+	}
+
+	for i, st := range obj.Status.Items {
+		prev := cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{}
+		if i < len(before) {
+			prev = before[i]
+		}
+		started := st.StartTime != nil && !st.StartTime.Equal(prev.StartTime)
+		item := fmt.Sprintf("%s item %d (%s) in phase %q", st.Action, st.Index, st.Kind, st.Phase)
+
+		if started {
+			s.Event(obj, corev1.EventTypeNormal, EventReasonItemStarted, fmt.Sprintf("Started %s, attempt %d%s", item, st.Attempts, suffix))
+			if s.targets && !obj.Spec.DryRun {
+				s.recordObjects(obj, st.Objects)
+			}
+		}
+
+		switch {
+		case st.State == cleanupv1alpha1.ItemStateCompleted && (started || prev.State != st.State):
+			s.Event(obj, corev1.EventTypeNormal, EventReasonItemCompleted, fmt.Sprintf("Completed %s with %d object(s)%s", item, len(st.Objects), suffix))
+		case st.State == cleanupv1alpha1.ItemStateFailed && (started || prev.State != st.State):
+			s.Event(obj, corev1.EventTypeWarning, EventReasonItemFailed, fmt.Sprintf("Failed %s: %s%s", item, st.Message, suffix))
+		case started && st.State == cleanupv1alpha1.ItemStatePending && st.NextRetryTime != nil:
+			s.Event(obj, corev1.EventTypeWarning, EventReasonItemRetrying, fmt.Sprintf("Retrying %s at %s: %s%s", item, st.NextRetryTime.UTC().Format("15:04:05"), st.Message, suffix))
+		}
+	}
+}
+
+// recordObjects records an event on each object with the result of the action of a PreClusterDestroyCleanup on it.
+func (s *EventService) recordObjects(obj *cleanupv1alpha1.PreClusterDestroyCleanup, objects []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) {
+	for _, o := range objects {
+		ref := &corev1.ObjectReference{
+			APIVersion: o.APIVersion,
+			Kind:       o.Kind,
+			Namespace:  o.Namespace,
+			Name:       o.Name,
+			UID:        o.UID,
+		}
+		by := fmt.Sprintf("by PreClusterDestroyCleanup %s/%s", obj.GetNamespace(), obj.GetName())
+
+		switch o.Result {
+		case cleanupv1alpha1.ResultSkipped:
+			continue
+		case cleanupv1alpha1.ResultFailed:
+			s.recorder.Event(ref, corev1.EventTypeWarning, EventReasonCleanupFailed, fmt.Sprintf("Cleanup %s failed: %s", by, o.Message))
+		default:
+			s.recorder.Event(ref, corev1.EventTypeNormal, o.Result, fmt.Sprintf("%s %s", o.Result, by))
+		}
+	}
+}
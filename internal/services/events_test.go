@@ -0,0 +1,123 @@
+package services
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+var _ = Describe("EventService", func() {
+	var (
+		recorder *record.FakeRecorder
+		obj      *cleanupv1alpha1.PreClusterDestroyCleanup
+		started  metav1.Time
+	)
+
+	// drain returns the events recorded so far.
+	drain := func() []string {
+		events := []string{}
+		for len(recorder.Events) > 0 {
+			events = append(events, <-recorder.Events)
+		}
+		return events
+	}
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		started = metav1.NewTime(time.Now())
+		obj = &cleanupv1alpha1.PreClusterDestroyCleanup{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cleanup", Namespace: "default"},
+			Status: cleanupv1alpha1.PreClusterDestroyCleanupStatus{
+				Items: []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{
+					{
+						Index:     0,
+						Kind:      "Deployment",
+						Action:    cleanupv1alpha1.ActionScaleToZero,
+						State:     cleanupv1alpha1.ItemStateCompleted,
+						StartTime: &started,
+						Attempts:  1,
+						Objects: []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{
+							{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "apps", Name: "web", Result: cleanupv1alpha1.ResultScaled},
+							{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "kube-system", Name: "dns", Result: cleanupv1alpha1.ResultSkipped},
+						},
+					},
+					{
+						Index:     1,
+						Kind:      "Pod",
+						Action:    cleanupv1alpha1.ActionDelete,
+						State:     cleanupv1alpha1.ItemStateFailed,
+						StartTime: &started,
+						Attempts:  1,
+						Message:   "forbidden",
+					},
+				},
+			},
+		}
+	})
+
+	Describe("RecordProgress", func() {
+		It("should record the start and outcome of the items started since before", func() {
+			NewEventService(recorder, false).RecordProgress(obj, nil)
+
+			events := drain()
+			Expect(events).To(HaveLen(4))
+			Expect(events[0]).To(HavePrefix("Normal " + EventReasonItemStarted))
+			Expect(events[1]).To(HavePrefix("Normal " + EventReasonItemCompleted))
+			Expect(events[2]).To(HavePrefix("Normal " + EventReasonItemStarted))
+			Expect(events[3]).To(HavePrefix("Warning " + EventReasonItemFailed))
+			Expect(events[3]).To(ContainSubstring("forbidden"))
+		})
+
+		It("should not record items that did not change", func() {
+			before := []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{}
+			for _, st := range obj.Status.Items {
+				before = append(before, *st.DeepCopy())
+			}
+
+			NewEventService(recorder, true).RecordProgress(obj, before)
+			Expect(drain()).To(BeEmpty())
+		})
+
+		It("should record a retry of a failed item", func() {
+			next := metav1.NewTime(started.Add(DefaultBackoff))
+			obj.Status.Items[1].State = cleanupv1alpha1.ItemStatePending
+			obj.Status.Items[1].NextRetryTime = &next
+
+			NewEventService(recorder, false).RecordProgress(obj, obj.Status.Items[:1])
+
+			events := drain()
+			Expect(events).To(HaveLen(2))
+			Expect(events[0]).To(HavePrefix("Normal " + EventReasonItemStarted))
+			Expect(events[1]).To(HavePrefix("Warning " + EventReasonItemRetrying))
+		})
+
+		It("should record events on the objects acted on, except skipped ones", func() {
+			obj.Status.Items = obj.Status.Items[:1]
+			NewEventService(recorder, true).RecordProgress(obj, nil)
+
+			events := drain()
+			Expect(events).To(HaveLen(3))
+			Expect(events[1]).To(HavePrefix("Normal " + cleanupv1alpha1.ResultScaled))
+			Expect(events[1]).To(ContainSubstring("default/test-cleanup"))
+		})
+
+		It("should not record events on the objects in dry run mode", func() {
+			obj.Spec.DryRun = true
+			obj.Status.Items = obj.Status.Items[:1]
+			NewEventService(recorder, true).RecordProgress(obj, nil)
+
+			events := drain()
+			Expect(events).To(HaveLen(2))
+			Expect(events[0]).To(HaveSuffix("(dry run)"))
+		})
+
+		It("should record nothing without a recorder", func() {
+			Expect(func() { NewEventService(nil, true).RecordProgress(obj, nil) }).NotTo(Panic())
+		})
+	})
+})
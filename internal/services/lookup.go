@@ -2,49 +2,91 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"strings"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/scale"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
 )
 
+// DefaultPageSize is the number of objects listed per request, unless set globally or by the item.
+const DefaultPageSize int64 = 500
+
 // LookupService provides methods to look up GroupVersionKind and CustomResourceDefinitions (CRDs).
 type LookupService struct {
-	client client.Client
-	config *rest.Config
-	logger logr.Logger
+	client    client.Client
+	reader    client.Reader // reader lists objects page by page from the API server, see ListResourcePages
+	config    *rest.Config
+	discovery discovery.CachedDiscoveryInterface
+	mapper    meta.RESTMapper                      // mapper resolves kinds, resources and short names, see restMapper
+	crds      map[string][]schema.GroupVersionKind // crds caches the kinds found by category for the lifetime of the service
+	pageSize  int64                                // pageSize is the number of objects listed per request, see PageSize
+	logger    logr.Logger
 }
 
 // NewLookupService creates a new LookupService instance.
-func NewLookupService(ctx context.Context, client client.Client, config *rest.Config) *LookupService {
+// Objects are only listed through opts.Reader, which must read from the API server, see ListResourcePages.
+func NewLookupService(ctx context.Context, client client.Client, config *rest.Config, opts Options) *LookupService {
 	return &LookupService{
-		client: client,
-		config: config,
-		logger: log.FromContext(ctx),
+		client:   client,
+		reader:   opts.Reader,
+		config:   config,
+		pageSize: opts.PageSize,
+		logger:   log.FromContext(ctx),
 	}
 }
 
-// LookupGroupKind attempts to find the GroupVersionKind for a given kind.
-// It first tries to find it directly by kind, and if that fails, it tries to find it by group and kind.
+// LookupGroupKind attempts to find the GroupVersionKind for a given kind, the way kubectl resolves its resource arguments.
+// The kind can be given as a kind, a plural or singular resource name, or a short name, e.g., "Deployment", "deployments"
+// or "deploy", optionally qualified with its group, or its version and group, e.g., "deploy.apps" or "Provider.v1.pkg.crossplane.io".
+// It returns the preferred version of the kind unless a version is given.
+// It returns an error listing the candidates if the kind matches kinds in more than one group, other than the core group.
 func (s *LookupService) LookupGroupKind(kind string) (schema.GroupVersionKind, error) {
-	mapper := s.client.RESTMapper()
+	mapper, err := s.restMapper()
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
 
-	// try to find the resource directly by kind
-	k, err := mapper.KindFor(schema.GroupVersionResource{Resource: kind})
-	if err == nil {
-		return k, nil
+	// try to find the kind as a resource, fully qualified first, then as a resource of a group
+	gvr, gr := schema.ParseResourceArg(kind)
+	resources := []schema.GroupVersionResource{gr.WithVersion("")}
+	if gvr != nil {
+		resources = append([]schema.GroupVersionResource{*gvr}, resources...)
+	}
+	for _, r := range resources {
+		gvks, err := mapper.KindsFor(r)
+		if err == nil && len(gvks) > 0 {
+			return preferredKind(kind, gvks)
+		}
 	}
 
-	// if not found, try to find it by group and kind
-	gk := schema.ParseGroupKind(kind)
-	m, err := mapper.RESTMapping(gk)
+	// if not found, try to find it by kind, e.g., when the singular name of the resource differs from the kind
+	gvk, gk := schema.ParseKindArg(kind)
+	versions := []string{}
+	if gvk != nil {
+		gk = gvk.GroupKind()
+		versions = append(versions, gvk.Version)
+	}
+	m, err := mapper.RESTMapping(gk, versions...)
 	if err == nil {
 		return m.GroupVersionKind, nil
 	}
@@ -52,15 +94,125 @@ func (s *LookupService) LookupGroupKind(kind string) (schema.GroupVersionKind, e
 	return schema.GroupVersionKind{}, fmt.Errorf("failed to find mapping for kind %s: %w", kind, err)
 }
 
+// preferredKind returns the first of the kinds a name was resolved to, in order of preference.
+// Like kubectl, the core group wins over other groups, e.g., "events" is the core Event rather than Event.events.k8s.io.
+// Otherwise it returns an error listing the candidates if they belong to more than one group.
+func preferredKind(name string, gvks []schema.GroupVersionKind) (schema.GroupVersionKind, error) {
+	if i := slices.IndexFunc(gvks, func(gvk schema.GroupVersionKind) bool { return gvk.Group == "" }); i >= 0 {
+		return gvks[i], nil
+	}
+
+	candidates := []string{}
+	for _, gvk := range gvks {
+		if c := gvk.GroupKind().String(); !slices.Contains(candidates, c) {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) > 1 {
+		slices.Sort(candidates)
+		return schema.GroupVersionKind{}, fmt.Errorf("kind %s is ambiguous, it matches %s: qualify it with its group", name, strings.Join(candidates, ", "))
+	}
+	return gvks[0], nil
+}
+
+// restMapper returns a RESTMapper for every kind served by the cluster, that also expands short names,
+// built from discovery and cached for the lifetime of the service.
+func (s *LookupService) restMapper() (meta.RESTMapper, error) {
+	if s.mapper == nil {
+		dc, err := s.discoveryClient()
+		if err != nil {
+			return nil, err
+		}
+
+		resources, err := restmapper.GetAPIGroupResources(dc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover resources: %w", err)
+		}
+		s.mapper = restmapper.NewShortcutExpander(restmapper.NewDiscoveryRESTMapper(resources), dc, func(warning string) {
+			s.logger.Info("Resolved kind with a warning", "warning", warning)
+		})
+	}
+	return s.mapper, nil
+}
+
+// LookupScaleResource returns the REST mapping of a kind that exposes a scale subresource.
+// If the GroupVersionKind does not specify a version, the kind is resolved with LookupGroupKind first.
+// It returns an error if the kind cannot be found, or discovery reports no scale subresource for it.
+func (s *LookupService) LookupScaleResource(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	if gvk.Version == "" {
+		resolved, err := s.LookupGroupKind(gvk.GroupKind().String())
+		if err != nil {
+			return nil, err
+		}
+		gvk = resolved
+	}
+
+	mapping, err := s.client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find mapping for kind %s: %w", gvk.Kind, err)
+	}
+
+	dc, err := s.discoveryClient()
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := dc.ServerResourcesForGroupVersion(mapping.GroupVersionKind.GroupVersion().String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover resources of %s: %w", mapping.GroupVersionKind.GroupVersion(), err)
+	}
+
+	subresource := mapping.Resource.Resource + "/scale"
+	if !slices.ContainsFunc(resources.APIResources, func(r metav1.APIResource) bool { return r.Name == subresource }) {
+		return nil, fmt.Errorf("scaling is not supported for kind %s: no scale subresource", gvk.Kind)
+	}
+
+	return mapping, nil
+}
+
+// ScalesGetter returns a client for the scale subresource of any kind that exposes one.
+func (s *LookupService) ScalesGetter() (scale.ScalesGetter, error) {
+	dc, err := s.discoveryClient()
+	if err != nil {
+		return nil, err
+	}
+
+	scales, err := scale.NewForConfig(rest.CopyConfig(s.config), s.client.RESTMapper(), dynamic.LegacyAPIPathResolverFunc, scale.NewDiscoveryScaleKindResolver(dc))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scale client: %w", err)
+	}
+	return scales, nil
+}
+
+// discoveryClient returns a discovery client for the cluster, cached for the lifetime of the service.
+func (s *LookupService) discoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	if s.discovery == nil {
+		dc, err := discovery.NewDiscoveryClientForConfig(s.config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create discovery client: %w", err)
+		}
+		s.discovery = memory.NewMemCacheClient(dc)
+	}
+	return s.discovery, nil
+}
+
 // LookupCrdsByCategory looks up CustomResourceDefinitions (CRDs) by their category.
-// It returns a slice of GroupVersionKind for CRDs that match the specified category.
+// It returns a slice of GroupVersionKind for CRDs that match the specified category, with the version resolved by CrdVersion.
+// CRDs that are not established yet, or serve no version, are skipped. The result is cached for the lifetime of the service.
 func (s *LookupService) LookupCrdsByCategory(ctx context.Context, category string) ([]schema.GroupVersionKind, error) {
+	key := strings.ToLower(category)
+	if gvks, ok := s.crds[key]; ok {
+		return gvks, nil
+	}
+
 	crdClient, err := apiextclient.NewForConfig(s.config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CRD client: %w", err)
 	}
 
+	ctx, span := StartSpan(ctx, "LookupCrdsByCategory", attribute.String("quartz.category", category))
 	crds, err := crdClient.ApiextensionsV1().CustomResourceDefinitions().List(ctx, metav1.ListOptions{})
+	EndSpan(span, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list CRDs: %w", err)
 	}
@@ -68,44 +220,426 @@ func (s *LookupService) LookupCrdsByCategory(ctx context.Context, category strin
 	s.logger.Info("Looking up CRDs by category", "category", category)
 	gvks := []schema.GroupVersionKind{}
 	for _, crd := range crds.Items {
-		if slices.ContainsFunc(crd.Spec.Names.Categories, func(c string) bool {
+		if !slices.ContainsFunc(crd.Spec.Names.Categories, func(c string) bool {
 			return strings.EqualFold(c, category)
 		}) {
-			s.logger.Info("Found CRD matching category", "category", category, "name", crd.Name, "group", crd.Spec.Group, "version", crd.Spec.Versions[0].Name, "kind", crd.Spec.Names.Kind)
-			gvks = append(gvks, schema.GroupVersionKind{
-				Group:   crd.Spec.Group,
-				Version: crd.Spec.Versions[0].Name, // Use the first version for simplicity
-				Kind:    crd.Spec.Names.Kind,
-			})
+			continue
+		}
+
+		if !CrdEstablished(&crd) {
+			s.logger.Info("Skipping CRD that is not established", "category", category, "name", crd.Name)
+			continue
+		}
+
+		version := CrdVersion(&crd)
+		if version == "" {
+			s.logger.Info("Skipping CRD that serves no version", "category", category, "name", crd.Name)
+			continue
 		}
+
+		s.logger.Info("Found CRD matching category", "category", category, "name", crd.Name, "group", crd.Spec.Group, "version", version, "kind", crd.Spec.Names.Kind)
+		gvks = append(gvks, schema.GroupVersionKind{
+			Group:   crd.Spec.Group,
+			Version: version,
+			Kind:    crd.Spec.Names.Kind,
+		})
 	}
 
+	if s.crds == nil {
+		s.crds = map[string][]schema.GroupVersionKind{}
+	}
+	s.crds[key] = gvks
 	return gvks, nil
 }
 
+// LookupKindsByCategory resolves a category to the kinds that advertise it in discovery, like "kubectl get <category>",
+// whether they are built-in, aggregated or custom resources. Only kinds whose resources can be listed and deleted are returned,
+// and if namespaced is true, only namespaced kinds. Kinds defined by a CRD use the version chosen by CrdVersion,
+// other kinds the preferred version of their group. Groups that fail discovery are logged and skipped.
+func (s *LookupService) LookupKindsByCategory(ctx context.Context, category string, namespaced bool) ([]schema.GroupVersionKind, error) {
+	ctx, span := StartSpan(ctx, "LookupKindsByCategory", attribute.String("quartz.category", category))
+	gvks, err := s.lookupKindsByCategory(ctx, category, namespaced)
+	span.SetAttributes(attribute.Int("quartz.kinds", len(gvks)))
+	EndSpan(span, err)
+	return gvks, err
+}
+
+// lookupKindsByCategory resolves a category to the kinds that advertise it in discovery.
+func (s *LookupService) lookupKindsByCategory(ctx context.Context, category string, namespaced bool) ([]schema.GroupVersionKind, error) {
+	dc, err := s.discoveryClient()
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := dc.ServerPreferredResources()
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, fmt.Errorf("failed to discover resources: %w", err)
+		}
+		s.logger.Info("Skipping API groups that failed discovery", "error", err.Error())
+	}
+
+	crds, err := s.LookupCrdsByCategory(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+	versions := map[schema.GroupKind]string{}
+	for _, gvk := range crds {
+		versions[gvk.GroupKind()] = gvk.Version
+	}
+
+	gvks := []schema.GroupVersionKind{}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") || (namespaced && !r.Namespaced) {
+				continue // subresources, or cluster scoped resources when looking in namespaces
+			}
+			if !slices.ContainsFunc(r.Categories, func(c string) bool { return strings.EqualFold(c, category) }) {
+				continue
+			}
+			if !slices.Contains(r.Verbs, "list") || !slices.Contains(r.Verbs, "delete") {
+				continue
+			}
+
+			gvk := gv.WithKind(r.Kind)
+			if v, ok := versions[gvk.GroupKind()]; ok {
+				gvk.Version = v
+			}
+			s.logger.Info("Found kind matching category", "category", category, "group", gvk.Group, "version", gvk.Version, "kind", gvk.Kind)
+			gvks = append(gvks, gvk)
+		}
+	}
+
+	return gvks, nil
+}
+
+// CrdEstablished reports whether a CustomResourceDefinition is established, i.e. its resources can be served.
+func CrdEstablished(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for _, c := range crd.Status.Conditions {
+		if c.Type == apiextensionsv1.Established {
+			return c.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// CrdVersion returns the version to list the resources of a CustomResourceDefinition with:
+// the storage version if it is served, otherwise the first served version, or "" if no version is served.
+func CrdVersion(crd *apiextensionsv1.CustomResourceDefinition) string {
+	served := ""
+	for _, v := range crd.Spec.Versions {
+		if !v.Served {
+			continue
+		}
+		if v.Storage {
+			return v.Name
+		}
+		if served == "" {
+			served = v.Name
+		}
+	}
+	return served
+}
+
+// SelectorListOptions returns the list options for the label and field selectors, and the page size, of a PreClusterDestroyCleanupItem.
+// It returns an error if either selector cannot be parsed. Field selectors are evaluated by the API server,
+// so the options must be used with a client that reads from it, see Options.Reader.
+func SelectorListOptions(item cleanupv1alpha1.PreClusterDestroyCleanupItem) ([]client.ListOption, error) {
+	opts := []client.ListOption{}
+
+	if item.LabelSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(item.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector: %w", err)
+		}
+		opts = append(opts, client.MatchingLabelsSelector{Selector: selector})
+	}
+
+	if item.FieldSelector != "" {
+		selector, err := fields.ParseSelector(item.FieldSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field selector %q: %w", item.FieldSelector, err)
+		}
+		opts = append(opts, client.MatchingFieldsSelector{Selector: selector})
+	}
+
+	if item.PageSize > 0 {
+		opts = append(opts, client.Limit(item.PageSize))
+	}
+
+	return opts, nil
+}
+
+// TargetsNamespaces reports whether a PreClusterDestroyCleanupItem is scoped to namespaces, rather than
+// all namespaces or cluster scoped resources.
+func TargetsNamespaces(item cleanupv1alpha1.PreClusterDestroyCleanupItem) bool {
+	return item.Namespace != "" || len(item.Namespaces) > 0 || item.NamespaceSelector != nil
+}
+
+// LookupNamespaces resolves the namespaces targeted by a PreClusterDestroyCleanupItem.
+// The result is the union of namespace, namespaces and the namespaces whose labels match namespaceSelector, sorted by name.
+// If none of them are set it returns a single empty namespace, meaning all namespaces or a cluster scoped resource.
+// A namespaceSelector that matches nothing, with no explicit namespaces, returns an empty slice.
+func (s *LookupService) LookupNamespaces(ctx context.Context, item cleanupv1alpha1.PreClusterDestroyCleanupItem) ([]string, error) {
+	ctx, span := StartSpan(ctx, "LookupNamespaces", AttributeKind.String(item.Kind), AttributeNamespace.String(item.Namespace))
+	namespaces, err := s.lookupNamespaces(ctx, item)
+	span.SetAttributes(attribute.Int("quartz.namespaces", len(namespaces)))
+	EndSpan(span, err)
+	return namespaces, err
+}
+
+// lookupNamespaces resolves the namespaces targeted by a PreClusterDestroyCleanupItem.
+func (s *LookupService) lookupNamespaces(ctx context.Context, item cleanupv1alpha1.PreClusterDestroyCleanupItem) ([]string, error) {
+	if !TargetsNamespaces(item) {
+		return []string{""}, nil
+	}
+
+	namespaces := []string{}
+	if item.Namespace != "" {
+		namespaces = append(namespaces, item.Namespace)
+	}
+	for _, ns := range item.Namespaces {
+		if ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	if item.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(item.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace selector: %w", err)
+		}
+
+		list := &corev1.NamespaceList{}
+		if err := s.client.List(ctx, list, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, fmt.Errorf("failed to list namespaces matching %s: %w", selector, err)
+		}
+		for _, ns := range list.Items {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+
+	slices.Sort(namespaces)
+	return slices.Compact(namespaces), nil
+}
+
+// Target holds what a PreClusterDestroyCleanupItem acts on, resolved once per item by LookupTarget.
+type Target struct {
+	Kinds      []schema.GroupVersionKind // Kinds acted on, the kind of the item or the kinds of its category
+	Namespaces []string                  // Namespaces targeted by the item, see LookupNamespaces
+	Name       string                    // Name of the object acted on, or empty for every object of the kinds
+}
+
+// SelectsCategory reports whether a PreClusterDestroyCleanupItem whose kind resolved to gvk deletes the resources of every
+// kind in its category, ex. "kubectl get managed": when it has no kind or, as it did before kinds were discovered,
+// the CustomResourceDefinition kind.
+func SelectsCategory(gvk schema.GroupVersionKind, item cleanupv1alpha1.PreClusterDestroyCleanupItem) bool {
+	return item.Category != "" && item.Action == cleanupv1alpha1.ActionDelete && (gvk.Kind == "" || gvk.Kind == CustomResourceDefinitionKind)
+}
+
+// LookupTarget resolves the kinds and namespaces a PreClusterDestroyCleanupItem whose kind resolved to gvk acts on.
+// Items that select a category act on every object of the kinds in the category, see SelectsCategory.
+func (s *LookupService) LookupTarget(ctx context.Context, gvk schema.GroupVersionKind, item cleanupv1alpha1.PreClusterDestroyCleanupItem) (Target, error) {
+	target := Target{Kinds: []schema.GroupVersionKind{gvk}, Name: item.Name}
+
+	namespaces, err := s.LookupNamespaces(ctx, item)
+	if err != nil {
+		return target, fmt.Errorf("failed to lookup namespaces for %s: %w", item.Kind, err)
+	}
+	target.Namespaces = namespaces
+
+	if SelectsCategory(gvk, item) {
+		gvks, err := s.LookupKindsByCategory(ctx, item.Category, TargetsNamespaces(item))
+		if err != nil {
+			return target, fmt.Errorf("failed to lookup kinds by category %s: %w", item.Category, err)
+		}
+		target.Kinds = gvks
+		target.Name = "" // a category selects every object of its kinds
+	}
+
+	return target, nil
+}
+
 // ListResources lists all resources of a specific GroupVersionKind in a given namespace.
-// It returns a PartialObjectMetadataList containing the resources found.
+// Additional list options, such as label or field selectors, can be given to narrow the results.
+// It returns a PartialObjectMetadataList containing the resources found, read page by page with ListResourcePages.
 // If the GroupVersionKind does not specify a version, it defaults to "v1".
-func (s *LookupService) ListResources(ctx context.Context, gvk schema.GroupVersionKind, ns string) (*metav1.PartialObjectMetadataList, error) {
+func (s *LookupService) ListResources(ctx context.Context, gvk schema.GroupVersionKind, ns string, opts ...client.ListOption) (*metav1.PartialObjectMetadataList, error) {
 	list := &metav1.PartialObjectMetadataList{}
+	err := s.ListResourcePages(ctx, gvk, ns, func(page *metav1.PartialObjectMetadataList) error {
+		list.TypeMeta = page.TypeMeta
+		list.Items = append(list.Items, page.Items...)
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListResourcePages lists the resources of a specific GroupVersionKind in a given namespace page by page,
+// calling fn with each page as it arrives, so that only one page is held in memory at a time.
+// Pages hold at most the limit given with the list options, or the page size of the service.
+// Listing stops at the first error returned by fn, which is returned as is.
+// Pages are read with the reader of the service, see Options.Reader. It returns an error if the service has none,
+// rather than risk listing through a cache, which ignores continue tokens and would silently return the first page only.
+// If the GroupVersionKind does not specify a version, it defaults to "v1".
+func (s *LookupService) ListResourcePages(ctx context.Context, gvk schema.GroupVersionKind, ns string, fn func(*metav1.PartialObjectMetadataList) error, opts ...client.ListOption) error {
+	if s.reader == nil {
+		return fmt.Errorf("failed to list %s in namespace %s: listing page by page requires a reader of the API server, see Options.Reader", gvk.Kind, ns)
+	}
 
 	v := gvk.Version
 	if v == "" {
 		v = "v1" // Default to v1 if no version is specified
 	}
-	list.SetGroupVersionKind(schema.GroupVersionKind{
+	listGVK := schema.GroupVersionKind{
 		Group:   gvk.Group,
 		Version: v,
 		Kind:    gvk.Kind + "List",
-	})
+	}
 
-	opts := []client.ListOption{
-		client.InNamespace(ns),
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	limit := listOpts.Limit
+	if limit <= 0 {
+		limit = s.PageSize()
 	}
+	opts = append([]client.ListOption{client.InNamespace(ns)}, opts...)
+	opts = append(opts, client.Limit(limit))
+
+	token := ""
+	for page := 1; ; page++ {
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(listGVK)
+
+		ctx, span := StartSpan(ctx, "ListResources", AttributeKind.String(gvk.Kind), AttributeNamespace.String(ns), attribute.Int("quartz.page", page))
+		err := s.reader.List(ctx, list, append(opts, client.Continue(token))...)
+		span.SetAttributes(attribute.Int("quartz.objects", len(list.Items)))
+		EndSpan(span, err)
+		if err != nil {
+			return fmt.Errorf("failed to list %s in namespace %s: %w", gvk.Kind, ns, err)
+		}
 
-	if err := s.client.List(ctx, list, opts...); err != nil {
-		return nil, fmt.Errorf("failed to list %s in namespace %s: %w", gvk.Kind, ns, err)
+		if err := fn(list); err != nil {
+			return err
+		}
+
+		token = list.GetContinue()
+		if token == "" {
+			return nil
+		}
 	}
+}
 
-	return list, nil
+// PageSize returns the number of objects listed per request when the list options do not set a limit,
+// Options.PageSize or DefaultPageSize if it is not set.
+func (s *LookupService) PageSize() int64 {
+	if s.pageSize <= 0 {
+		return DefaultPageSize
+	}
+	return s.pageSize
+}
+
+// GetResource returns the metadata of the named resource of a kind in a given namespace.
+func (s *LookupService) GetResource(ctx context.Context, gvk schema.GroupVersionKind, ns string, name string) (*metav1.PartialObjectMetadata, error) {
+	item := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+	item.SetGroupVersionKind(gvk)
+	ctx, span := StartSpan(ctx, "GetResource", AttributeKind.String(gvk.Kind), AttributeNamespace.String(ns), AttributeName.String(name))
+	err := s.client.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, item)
+	EndSpan(span, err)
+	if err != nil {
+		return item, fmt.Errorf("failed to get %s/%s: %w", ns, name, err)
+	}
+	return item, nil
+}
+
+// ObjectFunc performs an action on an object matched by an item, returning the status of the object and any error encountered.
+type ObjectFunc func(ctx context.Context, gvk schema.GroupVersionKind, obj *metav1.PartialObjectMetadata) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error)
+
+// ForEachObject calls fn with each object of the target of a PreClusterDestroyCleanupItem, in each namespace of the target,
+// narrowed by the selectors of the item, see ForEachObjectIn. It returns the status of each object and any errors encountered.
+func (s *LookupService) ForEachObject(ctx context.Context, target Target, item cleanupv1alpha1.PreClusterDestroyCleanupItem, deny DenyList, fn ObjectFunc) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	opts, err := SelectorListOptions(item)
+	if err != nil {
+		return nil, err
+	}
+
+	protection, err := NewProtection(deny, item.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(target.Namespaces) == 0 {
+		s.logger.Info("No namespaces found for item", "kind", item.Kind, "selector", item.NamespaceSelector)
+		return nil, nil // Nothing to do
+	}
+
+	var results []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus
+	errs := []error{}
+	for _, ns := range target.Namespaces {
+		for _, gvk := range target.Kinds {
+			r, err := s.ForEachObjectIn(ctx, gvk, ns, target.Name, protection, fn, opts...)
+			results = append(results, r...)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// ForEachObjectIn calls fn with the named object of a kind in a namespace or, if name is empty, with each object of the kind
+// in the namespace narrowed by the list options. Objects are listed page by page with ListResourcePages, and each page is
+// acted on as it arrives. Objects protected by protection, or not in the approved plan of ctx, see WithApprovedObjects,
+// are skipped and reported with their reason, without calling fn.
+// It returns the status of each object and any errors encountered, acting on the remaining objects after an error.
+func (s *LookupService) ForEachObjectIn(ctx context.Context, gvk schema.GroupVersionKind, ns string, name string, protection *Protection, fn ObjectFunc, opts ...client.ListOption) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	var results []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus
+	errs := []error{}
+	visit := func(obj *metav1.PartialObjectMetadata) {
+		reason := protection.SkipReason(gvk, obj)
+		if reason == "" {
+			reason = NotApproved(ctx, obj)
+		}
+		if reason != "" {
+			s.logger.Info("Skipping protected item", "kind", gvk.Kind, "namespace", obj.GetNamespace(), "name", obj.GetName(), "reason", reason)
+			results = append(results, SkippedObjectStatus(gvk, obj, reason))
+			return
+		}
+
+		result, err := fn(ctx, gvk, obj)
+		results = append(results, result)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if name != "" {
+		obj, err := s.GetResource(ctx, gvk, ns, name)
+		if err != nil {
+			return []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{NewObjectStatus(gvk, obj, cleanupv1alpha1.ResultFailed, err)}, err
+		}
+		visit(obj)
+		return results, errors.Join(errs...)
+	}
+
+	err := s.ListResourcePages(ctx, gvk, ns, func(page *metav1.PartialObjectMetadataList) error {
+		for i := range page.Items {
+			visit(&page.Items[i])
+		}
+		return nil
+	}, opts...)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list resources of kind %s in namespace %s: %w", gvk.Kind, ns, err))
+	}
+
+	if len(results) == 0 && len(errs) == 0 {
+		s.logger.Info("No resources found", "kind", gvk.Kind, "namespace", ns)
+	}
+	return results, errors.Join(errs...)
 }
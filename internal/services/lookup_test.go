@@ -2,11 +2,17 @@ package services
 
 import (
 	"context"
+	"errors"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
 )
 
 var _ = Describe("LookupService", func() {
@@ -21,7 +27,7 @@ var _ = Describe("LookupService", func() {
 		c = testEnv.K8sClient
 
 		// Initialize the LookupService with a test config
-		lookupService = NewLookupService(ctx, c, testEnv.Cfg)
+		lookupService = NewLookupService(ctx, c, testEnv.Cfg, Options{Reader: c})
 	})
 
 	Describe("LookupGroupKind", func() {
@@ -47,12 +53,234 @@ var _ = Describe("LookupService", func() {
 			Expect(gvk.Version).To(Equal("v1"))
 		})
 
+		It("should resolve plural, singular and short names", func() {
+			for _, name := range []string{"deployments", "deployment", "deploy", "deploy.apps", "deployments.v1.apps", "Deployment.v1.apps"} {
+				gvk, err := lookupService.LookupGroupKind(name)
+				Expect(err).NotTo(HaveOccurred(), name)
+				Expect(gvk).To(Equal(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}), name)
+			}
+
+			gvk, err := lookupService.LookupGroupKind("pdb")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gvk).To(Equal(schema.GroupVersionKind{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"}))
+		})
+
+		It("should resolve custom resources by short name and fully qualified kind", func() {
+			t := testEnv.WithRandomSuffix()
+			crd := t.CustomResourceDefinition("Widget", t.FormatName("teardown"), "v1", "v2")
+			crd.Spec.Names.ShortNames = []string{t.FormatName("wdg")}
+			Expect(c.Create(ctx, crd)).To(Succeed())
+			DeferCleanup(func() { Expect(c.Delete(ctx, crd)).To(Succeed()) })
+
+			Eventually(func() error {
+				_, err := NewLookupService(ctx, c, testEnv.Cfg, Options{Reader: c}).LookupGroupKind("Widget.v2." + crd.Spec.Group)
+				return err
+			}).Should(Succeed())
+
+			lookup := NewLookupService(ctx, c, testEnv.Cfg, Options{Reader: c})
+			gvk, err := lookup.LookupGroupKind("Widget.v1." + crd.Spec.Group)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gvk).To(Equal(schema.GroupVersionKind{Group: crd.Spec.Group, Version: "v1", Kind: "Widget"}))
+
+			gvk, err = lookup.LookupGroupKind(crd.Spec.Names.ShortNames[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gvk.Kind).To(Equal("Widget"))
+			Expect(gvk.Group).To(Equal(crd.Spec.Group))
+		})
+
+		It("should prefer the core group over other groups, like kubectl", func() {
+			for _, name := range []string{"Event", "event", "events", "ev"} {
+				gvk, err := lookupService.LookupGroupKind(name)
+				Expect(err).NotTo(HaveOccurred(), name)
+				Expect(gvk).To(Equal(schema.GroupVersionKind{Version: "v1", Kind: "Event"}), name)
+			}
+
+			gvk, err := lookupService.LookupGroupKind("events.events.k8s.io")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gvk.Group).To(Equal("events.k8s.io"))
+		})
+
+		It("should list the candidates of an ambiguous kind", func() {
+			crds := []*apiextensionsv1.CustomResourceDefinition{}
+			for range 2 {
+				crd := testEnv.WithRandomSuffix().CustomResourceDefinition("Gadget", "test", "v1")
+				Expect(c.Create(ctx, crd)).To(Succeed())
+				DeferCleanup(func() { Expect(c.Delete(ctx, crd)).To(Succeed()) })
+				crds = append(crds, crd)
+			}
+
+			Eventually(func(g Gomega) {
+				lookup := NewLookupService(ctx, c, testEnv.Cfg, Options{Reader: c})
+				for _, crd := range crds {
+					_, err := lookup.LookupGroupKind("Gadget." + crd.Spec.Group)
+					g.Expect(err).NotTo(HaveOccurred())
+				}
+			}).Should(Succeed())
+
+			_, err := NewLookupService(ctx, c, testEnv.Cfg, Options{Reader: c}).LookupGroupKind("gadgets")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("ambiguous"))
+			Expect(err.Error()).To(ContainSubstring("Gadget." + crds[0].Spec.Group))
+			Expect(err.Error()).To(ContainSubstring("Gadget." + crds[1].Spec.Group))
+		})
+
 		It("should return error for unknown kinds", func() {
 			_, err := lookupService.LookupGroupKind("UnknownKind")
 			Expect(err).To(HaveOccurred())
 		})
 	})
 
+	Describe("CrdVersion", func() {
+		It("should prefer the served storage version", func() {
+			crd := testEnv.CustomResourceDefinition("Widget", "test", "v1alpha1", "v1beta1", "v1")
+			Expect(CrdVersion(crd)).To(Equal("v1"))
+
+			crd.Spec.Versions[2].Served = false
+			Expect(CrdVersion(crd)).To(Equal("v1alpha1"))
+
+			crd.Spec.Versions[0].Served = false
+			Expect(CrdVersion(crd)).To(Equal("v1beta1"))
+
+			crd.Spec.Versions[1].Served = false
+			Expect(CrdVersion(crd)).To(BeEmpty())
+		})
+	})
+
+	Describe("LookupCrdsByCategory", func() {
+		It("should return the established CRDs of a category with their storage version", func() {
+			t := testEnv.WithRandomSuffix()
+			category := t.FormatName("teardown")
+			crd := t.CustomResourceDefinition("Widget", category, "v1alpha1", "v1")
+			other := t.CustomResourceDefinition("Gadget", t.FormatName("other"), "v1")
+			Expect(c.Create(ctx, crd)).To(Succeed())
+			Expect(c.Create(ctx, other)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(c.Delete(ctx, crd)).To(Succeed())
+				Expect(c.Delete(ctx, other)).To(Succeed())
+			})
+
+			Eventually(func() bool {
+				Expect(c.Get(ctx, client.ObjectKeyFromObject(crd), crd)).To(Succeed())
+				return CrdEstablished(crd)
+			}).Should(BeTrue())
+
+			gvks, err := lookupService.LookupCrdsByCategory(ctx, category)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gvks).To(ConsistOf(schema.GroupVersionKind{Group: crd.Spec.Group, Version: "v1", Kind: "Widget"}))
+		})
+
+		It("should skip CRDs that are not established", func() {
+			crd := testEnv.CustomResourceDefinition("Widget", "test", "v1")
+			Expect(CrdEstablished(crd)).To(BeFalse())
+
+			crd.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{
+				{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionFalse},
+			}
+			Expect(CrdEstablished(crd)).To(BeFalse())
+
+			crd.Status.Conditions[0].Status = apiextensionsv1.ConditionTrue
+			Expect(CrdEstablished(crd)).To(BeTrue())
+		})
+	})
+
+	Describe("LookupKindsByCategory", func() {
+		It("should resolve built-in categories like kubectl", func() {
+			gvks, err := lookupService.LookupKindsByCategory(ctx, "all", true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gvks).To(ContainElements(
+				schema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+				schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			))
+			Expect(gvks).NotTo(ContainElement(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}))
+		})
+
+		It("should resolve custom resources with their storage version, and leave out cluster scoped kinds in namespaces", func() {
+			t := testEnv.WithRandomSuffix()
+			category := t.FormatName("teardown")
+			crd := t.CustomResourceDefinition("Widget", category, "v1", "v2")
+			cluster := t.CustomResourceDefinition("ClusterWidget", category, "v1")
+			cluster.Spec.Scope = apiextensionsv1.ClusterScoped
+			Expect(c.Create(ctx, crd)).To(Succeed())
+			Expect(c.Create(ctx, cluster)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(c.Delete(ctx, crd)).To(Succeed())
+				Expect(c.Delete(ctx, cluster)).To(Succeed())
+			})
+
+			widget := schema.GroupVersionKind{Group: crd.Spec.Group, Version: "v2", Kind: "Widget"}
+			clusterWidget := schema.GroupVersionKind{Group: crd.Spec.Group, Version: "v1", Kind: "ClusterWidget"}
+			Eventually(func() []schema.GroupVersionKind {
+				gvks, err := NewLookupService(ctx, c, testEnv.Cfg, Options{Reader: c}).LookupKindsByCategory(ctx, category, false)
+				Expect(err).NotTo(HaveOccurred())
+				return gvks
+			}).Should(ConsistOf(widget, clusterWidget))
+
+			gvks, err := NewLookupService(ctx, c, testEnv.Cfg, Options{Reader: c}).LookupKindsByCategory(ctx, category, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gvks).To(ConsistOf(widget))
+		})
+
+		It("should return no kinds for an unknown category", func() {
+			gvks, err := lookupService.LookupKindsByCategory(ctx, "no-such-category", false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gvks).To(BeEmpty())
+		})
+	})
+
+	Describe("LookupTarget", func() {
+		It("should resolve the kind, namespaces and name of an item", func() {
+			gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:       "Deployment",
+				Namespaces: []string{"b", "a"},
+				Name:       "web",
+				Action:     cleanupv1alpha1.ActionScaleToZero,
+			}
+
+			target, err := lookupService.LookupTarget(ctx, gvk, item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target.Kinds).To(Equal([]schema.GroupVersionKind{gvk}))
+			Expect(target.Namespaces).To(Equal([]string{"a", "b"}))
+			Expect(target.Name).To(Equal("web"))
+		})
+
+		It("should resolve the kinds of the category of a delete item, for every object", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Category:  "all",
+				Namespace: "default",
+				Name:      "ignored",
+				Action:    cleanupv1alpha1.ActionDelete,
+			}
+
+			target, err := lookupService.LookupTarget(ctx, schema.GroupVersionKind{}, item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target.Kinds).To(ContainElement(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}))
+			Expect(target.Namespaces).To(Equal([]string{"default"}))
+			Expect(target.Name).To(BeEmpty())
+		})
+	})
+
+	Describe("LookupScaleResource", func() {
+		It("should find kinds with a scale subresource", func() {
+			for _, kind := range []string{"Deployment", "StatefulSet", "ReplicaSet", "ReplicationController"} {
+				mapping, err := lookupService.LookupScaleResource(schema.GroupVersionKind{Kind: kind})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mapping.GroupVersionKind.Kind).To(Equal(kind))
+			}
+
+			mapping, err := lookupService.LookupScaleResource(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mapping.Resource.Resource).To(Equal("deployments"))
+		})
+
+		It("should return error for kinds without a scale subresource", func() {
+			_, err := lookupService.LookupScaleResource(schema.GroupVersionKind{Version: "v1", Kind: "Service"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("scaling is not supported for kind Service"))
+		})
+	})
+
 	Describe("ListResources", func() {
 		It("should list resources of a specific kind in a namespace", func() {
 
@@ -83,5 +311,208 @@ var _ = Describe("LookupService", func() {
 			}
 			Expect(names).To(ContainElements(pod1.GetName(), pod2.GetName()))
 		})
+
+		It("should narrow the results with label and field selectors", func() {
+			t := testEnv.WithRandomSuffix()
+			ns := t.Namespace("lookupservice")
+			pod1 := t.Pod("test-pod-1", ns.GetName())
+			pod2 := t.Pod("test-pod-2", ns.GetName())
+
+			Expect(c.Create(ctx, ns)).To(Succeed())
+			Expect(c.Create(ctx, pod1)).To(Succeed())
+			Expect(c.Create(ctx, pod2)).To(Succeed())
+
+			gvk := schema.GroupVersionKind{
+				Group:   "",
+				Version: "v1",
+				Kind:    "Pod",
+			}
+
+			opts, err := SelectorListOptions(cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": pod1.GetName()}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			list, err := lookupService.ListResources(ctx, gvk, ns.GetName(), opts...)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list.Items).To(HaveLen(1))
+			Expect(list.Items[0].GetName()).To(Equal(pod1.GetName()))
+
+			opts, err = SelectorListOptions(cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				FieldSelector: "metadata.name=" + pod2.GetName(),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			list, err = lookupService.ListResources(ctx, gvk, ns.GetName(), opts...)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list.Items).To(HaveLen(1))
+			Expect(list.Items[0].GetName()).To(Equal(pod2.GetName()))
+		})
+
+		It("should list every page when the page size of the item is smaller than the results", func() {
+			t := testEnv.WithRandomSuffix()
+			ns := t.Namespace("lookupservice")
+			Expect(c.Create(ctx, ns)).To(Succeed())
+			for _, name := range []string{"test-pod-1", "test-pod-2", "test-pod-3"} {
+				Expect(c.Create(ctx, t.Pod(name, ns.GetName()))).To(Succeed())
+			}
+
+			gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+			opts, err := SelectorListOptions(cleanupv1alpha1.PreClusterDestroyCleanupItem{PageSize: 2})
+			Expect(err).NotTo(HaveOccurred())
+
+			sizes := []int{}
+			err = lookupService.ListResourcePages(ctx, gvk, ns.GetName(), func(page *metav1.PartialObjectMetadataList) error {
+				sizes = append(sizes, len(page.Items))
+				return nil
+			}, opts...)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sizes).To(Equal([]int{2, 1}))
+
+			list, err := lookupService.ListResources(ctx, gvk, ns.GetName(), opts...)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list.Items).To(HaveLen(3))
+		})
+
+		It("should use the page size of the service unless the item sets one", func() {
+			t := testEnv.WithRandomSuffix()
+			ns := t.Namespace("lookupservice")
+			Expect(c.Create(ctx, ns)).To(Succeed())
+			for _, name := range []string{"test-pod-1", "test-pod-2", "test-pod-3"} {
+				Expect(c.Create(ctx, t.Pod(name, ns.GetName()))).To(Succeed())
+			}
+
+			Expect(lookupService.PageSize()).To(Equal(DefaultPageSize))
+			lookupService.pageSize = 1
+
+			pages := 0
+			err := lookupService.ListResourcePages(ctx, schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, ns.GetName(), func(page *metav1.PartialObjectMetadataList) error {
+				pages++
+				return nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pages).To(Equal(3))
+
+			By("stopping at the first error returned for a page")
+			stop := errors.New("stop")
+			pages = 0
+			err = lookupService.ListResourcePages(ctx, schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, ns.GetName(), func(page *metav1.PartialObjectMetadataList) error {
+				pages++
+				return stop
+			})
+			Expect(err).To(MatchError(stop))
+			Expect(pages).To(Equal(1))
+		})
+	})
+
+	Describe("SelectorListOptions", func() {
+		It("should return an error for invalid selectors", func() {
+			_, err := SelectorListOptions(cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				LabelSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Bogus"}},
+				},
+			})
+			Expect(err).To(HaveOccurred())
+
+			_, err = SelectorListOptions(cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				FieldSelector: "metadata.name==a==b",
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("LookupNamespaces", func() {
+		It("should return a single empty namespace when the item is not scoped to namespaces", func() {
+			namespaces, err := lookupService.LookupNamespaces(ctx, cleanupv1alpha1.PreClusterDestroyCleanupItem{Kind: "Pod"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(namespaces).To(Equal([]string{""}))
+		})
+
+		It("should combine explicit namespaces with namespaces matching the selector", func() {
+			t := testEnv.WithRandomSuffix()
+			ns1 := t.Namespace("lookupservice-a")
+			ns2 := t.Namespace("lookupservice-b")
+			ns3 := t.Namespace("lookupservice-c")
+			ns2.Labels = map[string]string{"teardown": t.FormatName("lookupservice")}
+			ns3.Labels = map[string]string{"teardown": t.FormatName("lookupservice")}
+
+			Expect(c.Create(ctx, ns1)).To(Succeed())
+			Expect(c.Create(ctx, ns2)).To(Succeed())
+			Expect(c.Create(ctx, ns3)).To(Succeed())
+
+			namespaces, err := lookupService.LookupNamespaces(ctx, cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:              "Pod",
+				Namespace:         ns3.GetName(),
+				Namespaces:        []string{ns1.GetName()},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: ns2.Labels},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(namespaces).To(Equal([]string{ns1.GetName(), ns2.GetName(), ns3.GetName()}))
+		})
+
+		It("should return no namespaces when the selector matches nothing", func() {
+			namespaces, err := lookupService.LookupNamespaces(ctx, cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:              "Pod",
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"teardown": "does-not-exist"}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(namespaces).To(BeEmpty())
+		})
+
+		It("should return an error for an invalid selector", func() {
+			_, err := lookupService.LookupNamespaces(ctx, cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind: "Pod",
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "teardown", Operator: "Bogus"}},
+				},
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ForEachObject", func() {
+		It("should act on every page of objects in each namespace, skipping protected ones", func() {
+			t := testEnv.WithRandomSuffix()
+			ns1 := t.Namespace("lookupservice-1")
+			ns2 := t.Namespace("lookupservice-2")
+			for _, ns := range []*corev1.Namespace{ns1, ns2} {
+				Expect(c.Create(ctx, ns)).To(Succeed())
+				for _, name := range []string{"test-pod-1", "test-pod-2"} {
+					Expect(c.Create(ctx, t.Pod(name, ns.GetName()))).To(Succeed())
+				}
+			}
+
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:       "Pod",
+				Namespaces: []string{ns1.GetName(), ns2.GetName()},
+				PageSize:   1,
+				Exclude:    &cleanupv1alpha1.PreClusterDestroyCleanupExclude{Names: []string{t.FormatName("test-pod-2")}},
+			}
+			gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+			visited := []string{}
+			results, err := lookupService.ForEachObject(ctx, lookupTarget(ctx, gvk, item), item, DenyList{Namespaces: []string{ns2.GetName()}},
+				func(ctx context.Context, gvk schema.GroupVersionKind, obj *metav1.PartialObjectMetadata) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+					visited = append(visited, obj.GetNamespace()+"/"+obj.GetName())
+					return NewObjectStatus(gvk, obj, cleanupv1alpha1.ResultDeleted, nil), nil
+				})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(visited).To(ConsistOf(ns1.GetName() + "/" + t.FormatName("test-pod-1")))
+			Expect(results).To(HaveLen(4))
+			Expect(CountResults([]cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{{Objects: results}}, cleanupv1alpha1.ResultSkipped)).To(Equal(3))
+		})
+
+		It("should report a named object that cannot be found as failed", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{Kind: "Pod", Namespace: "default", Name: "does-not-exist"}
+			results, err := lookupService.ForEachObject(ctx, lookupTarget(ctx, schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, item), item, DenyList{},
+				func(ctx context.Context, gvk schema.GroupVersionKind, obj *metav1.PartialObjectMetadata) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+					Fail("should not be called for a missing object")
+					return cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{}, nil
+				})
+			Expect(err).To(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Result).To(Equal(cleanupv1alpha1.ResultFailed))
+			Expect(results[0].Name).To(Equal("does-not-exist"))
+		})
 	})
 })
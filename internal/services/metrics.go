@@ -0,0 +1,132 @@
+package services
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+const metricsNamespace = "quartz_cleanup"
+
+var (
+	// objectsTotal counts the objects acted on by cleanups, by result.
+	objectsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "objects_total",
+		Help:      "Number of objects acted on by PreClusterDestroyCleanups, by kind, action, cleanup and result.",
+	}, []string{"kind", "action", "cleanup", "result"})
+
+	// itemDuration observes how long items take from being performed until they complete or fail.
+	itemDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "item_duration_seconds",
+		Help:      "Time from performing the action of a PreClusterDestroyCleanup item until it completed or failed.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+	}, []string{"kind", "action", "cleanup", "state"})
+
+	// itemsPendingDeletion tracks the delete items of each cleanup that have not completed yet.
+	itemsPendingDeletion = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "items_pending_deletion",
+		Help:      "Number of delete items of a PreClusterDestroyCleanup that are pending or waiting for their objects to be deleted.",
+	}, []string{"cleanup"})
+
+	// runDuration observes how long cleanups take from performing their first item until the run finished.
+	runDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "duration_seconds",
+		Help:      "Time from performing the first item of a PreClusterDestroyCleanup until the run finished.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 14),
+	}, []string{"cleanup", "result"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(objectsTotal, itemDuration, itemsPendingDeletion, runDuration)
+}
+
+// MetricsName returns the value of the cleanup label of the metrics of a PreClusterDestroyCleanup.
+func MetricsName(key types.NamespacedName) string {
+	return key.String()
+}
+
+// ObserveProgress updates the metrics of a PreClusterDestroyCleanup by comparing the status of its items with
+// their status before they were processed. Objects are counted once, when the item acting on them is performed,
+// or when their result changes afterwards, e.g., when they are forced. Dry runs are not counted.
+func ObserveProgress(obj *cleanupv1alpha1.PreClusterDestroyCleanup, before []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) {
+	if obj.Spec.DryRun {
+		return
+	}
+
+	name := MetricsName(types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()})
+	pending := 0
+	for i, st := range obj.Status.Items {
+		prev := cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{}
+		if i < len(before) {
+			prev = before[i]
+		}
+		started := st.StartTime != nil && !st.StartTime.Equal(prev.StartTime)
+
+		results := map[string]string{}
+		if !started {
+			for _, o := range prev.Objects {
+				results[objectKey(o)] = o.Result
+			}
+		}
+		for _, o := range st.Objects {
+			if result, ok := results[objectKey(o)]; ok && result == o.Result {
+				continue
+			}
+			objectsTotal.WithLabelValues(o.Kind, st.Action, name, o.Result).Inc()
+		}
+
+		finished := st.State == cleanupv1alpha1.ItemStateCompleted || st.State == cleanupv1alpha1.ItemStateFailed
+		if finished && st.StartTime != nil && (started || prev.State != st.State) {
+			itemDuration.WithLabelValues(st.Kind, st.Action, name, st.State).Observe(time.Since(st.StartTime.Time).Seconds())
+		}
+
+		if st.Action == cleanupv1alpha1.ActionDelete && !finished {
+			pending++
+		}
+	}
+	itemsPendingDeletion.WithLabelValues(name).Set(float64(pending))
+}
+
+// ObserveRun records the duration of a finished run of a PreClusterDestroyCleanup, from its first performed item.
+// Dry runs, and runs that did not perform any item, are not observed.
+func ObserveRun(obj *cleanupv1alpha1.PreClusterDestroyCleanup, result string) {
+	if obj.Spec.DryRun {
+		return
+	}
+
+	var start *metav1.Time
+	for _, st := range obj.Status.Items {
+		if st.StartTime != nil && (start == nil || st.StartTime.Before(start)) {
+			start = st.StartTime
+		}
+	}
+	if start == nil {
+		return
+	}
+
+	name := MetricsName(types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()})
+	runDuration.WithLabelValues(name, result).Observe(time.Since(start.Time).Seconds())
+}
+
+// ForgetMetrics removes every series of a deleted PreClusterDestroyCleanup, so that they are not reported forever.
+func ForgetMetrics(key types.NamespacedName) {
+	labels := prometheus.Labels{"cleanup": MetricsName(key)}
+	objectsTotal.DeletePartialMatch(labels)
+	itemDuration.DeletePartialMatch(labels)
+	itemsPendingDeletion.DeletePartialMatch(labels)
+	runDuration.DeletePartialMatch(labels)
+}
+
+// objectKey identifies an object in the status of an item.
+func objectKey(o cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) string {
+	return o.APIVersion + "/" + o.Kind + "/" + o.Namespace + "/" + o.Name
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+var _ = Describe("Metrics", func() {
+	var (
+		obj     *cleanupv1alpha1.PreClusterDestroyCleanup
+		name    string
+		started metav1.Time
+	)
+
+	BeforeEach(func() {
+		t := testEnv.WithRandomSuffix()
+		started = metav1.NewTime(time.Now().Add(-time.Minute))
+		obj = &cleanupv1alpha1.PreClusterDestroyCleanup{
+			ObjectMeta: metav1.ObjectMeta{Name: t.FormatName("test-cleanup"), Namespace: "default"},
+			Status: cleanupv1alpha1.PreClusterDestroyCleanupStatus{
+				Items: []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{
+					{
+						Index:     0,
+						Kind:      "Deployment",
+						Action:    cleanupv1alpha1.ActionScaleToZero,
+						State:     cleanupv1alpha1.ItemStateCompleted,
+						StartTime: &started,
+						Objects: []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{
+							{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "apps", Name: "web", Result: cleanupv1alpha1.ResultScaled},
+							{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "kube-system", Name: "dns", Result: cleanupv1alpha1.ResultSkipped},
+						},
+					},
+					{
+						Index:     1,
+						Kind:      "Pod",
+						Action:    cleanupv1alpha1.ActionDelete,
+						State:     cleanupv1alpha1.ItemStateWaiting,
+						StartTime: &started,
+						Objects: []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{
+							{APIVersion: "v1", Kind: "Pod", Namespace: "apps", Name: "web-0", Result: cleanupv1alpha1.ResultDeleted},
+						},
+					},
+				},
+			},
+		}
+		name = MetricsName(types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()})
+	})
+
+	Describe("ObserveProgress", func() {
+		It("should count the objects of performed items by result", func() {
+			ObserveProgress(obj, nil)
+
+			Expect(testutil.ToFloat64(objectsTotal.WithLabelValues("Deployment", cleanupv1alpha1.ActionScaleToZero, name, cleanupv1alpha1.ResultScaled))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(objectsTotal.WithLabelValues("Deployment", cleanupv1alpha1.ActionScaleToZero, name, cleanupv1alpha1.ResultSkipped))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(objectsTotal.WithLabelValues("Pod", cleanupv1alpha1.ActionDelete, name, cleanupv1alpha1.ResultDeleted))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(itemsPendingDeletion.WithLabelValues(name))).To(Equal(1.0))
+		})
+
+		It("should only count objects again when their result changes", func() {
+			ObserveProgress(obj, nil)
+
+			before := []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{}
+			for _, st := range obj.Status.Items {
+				before = append(before, *st.DeepCopy())
+			}
+			obj.Status.Items[1].State = cleanupv1alpha1.ItemStateCompleted
+			obj.Status.Items[1].Objects[0].Result = cleanupv1alpha1.ResultForced
+			ObserveProgress(obj, before)
+
+			Expect(testutil.ToFloat64(objectsTotal.WithLabelValues("Deployment", cleanupv1alpha1.ActionScaleToZero, name, cleanupv1alpha1.ResultScaled))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(objectsTotal.WithLabelValues("Pod", cleanupv1alpha1.ActionDelete, name, cleanupv1alpha1.ResultForced))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(itemsPendingDeletion.WithLabelValues(name))).To(Equal(0.0))
+		})
+
+		It("should not count dry runs", func() {
+			obj.Spec.DryRun = true
+			ObserveProgress(obj, nil)
+
+			Expect(testutil.ToFloat64(objectsTotal.WithLabelValues("Deployment", cleanupv1alpha1.ActionScaleToZero, name, cleanupv1alpha1.ResultScaled))).To(Equal(0.0))
+		})
+	})
+
+	Describe("ObserveRun", func() {
+		It("should observe the duration of the run from its first item", func() {
+			ObserveRun(obj, "CompletedSuccessfully")
+			Expect(testutil.CollectAndCount(runDuration, metricsNamespace+"_duration_seconds")).To(BeNumerically(">=", 1))
+		})
+	})
+
+	Describe("ForgetMetrics", func() {
+		It("should remove every series of a cleanup", func() {
+			ObserveProgress(obj, nil)
+			ObserveRun(obj, "CompletedSuccessfully")
+			ForgetMetrics(types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()})
+
+			labels := prometheus.Labels{"cleanup": name}
+			Expect(objectsTotal.DeletePartialMatch(labels)).To(BeZero())
+			Expect(itemDuration.DeletePartialMatch(labels)).To(BeZero())
+			Expect(itemsPendingDeletion.DeletePartialMatch(labels)).To(BeZero())
+			Expect(runDuration.DeletePartialMatch(labels)).To(BeZero())
+		})
+	})
+})
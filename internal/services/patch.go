@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+// PatchService provides methods to apply the patch of a PreClusterDestroyCleanupItem to the resources it matches.
+type PatchService struct {
+	client client.Client
+	lookup *LookupService
+	deny   DenyList
+	logger logr.Logger
+}
+
+// NewPatchService creates a new PatchService instance.
+func NewPatchService(ctx context.Context, client client.Client, lookup *LookupService, deny DenyList) *PatchService {
+	return &PatchService{
+		client: client,
+		lookup: lookup,
+		deny:   deny,
+		logger: log.FromContext(ctx),
+	}
+}
+
+// PatchType returns the patch type for the type of a PreClusterDestroyCleanupPatch, defaulting to a JSON merge patch.
+func PatchType(patch cleanupv1alpha1.PreClusterDestroyCleanupPatch) (types.PatchType, error) {
+	switch patch.Type {
+	case "", cleanupv1alpha1.PatchTypeMerge:
+		return types.MergePatchType, nil
+	case cleanupv1alpha1.PatchTypeJSON:
+		return types.JSONPatchType, nil
+	default:
+		return "", fmt.Errorf("unsupported patch type %s", patch.Type)
+	}
+}
+
+// PatchItem applies the patch of a PreClusterDestroyCleanupItem to the objects of its target, see LookupTarget.
+// It returns the status of each object touched and any errors encountered while patching.
+// If dryRun is true, the patch is sent with DryRunAll so the API server validates it without persisting the changes.
+func (s *PatchService) PatchItem(ctx context.Context, dryRun bool, target Target, item cleanupv1alpha1.PreClusterDestroyCleanupItem) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	if item.Patch == nil || len(item.Patch.Data.Raw) == 0 {
+		return nil, fmt.Errorf("patch must be specified for item: %v", item)
+	}
+
+	pt, err := PatchType(*item.Patch)
+	if err != nil {
+		return nil, err
+	}
+	patch := client.RawPatch(pt, item.Patch.Data.Raw)
+
+	patchOpts := []client.PatchOption{}
+	if dryRun {
+		patchOpts = append(patchOpts, client.DryRunAll)
+	}
+
+	return s.lookup.ForEachObject(ctx, target, item, s.deny, func(ctx context.Context, gvk schema.GroupVersionKind, obj *metav1.PartialObjectMetadata) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+		if err := s.patchObject(ctx, dryRun, gvk, obj, patch, patchOpts); err != nil {
+			err = fmt.Errorf("failed to patch %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+			return NewObjectStatus(gvk, obj, cleanupv1alpha1.ResultFailed, err), err
+		}
+		return ObjectStatus(dryRun, gvk, obj, cleanupv1alpha1.ResultPatched, nil), nil
+	})
+}
+
+// patchObject applies a patch to a single object, in a span of its own.
+func (s *PatchService) patchObject(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, obj *metav1.PartialObjectMetadata, patch client.Patch, opts []client.PatchOption) error {
+	ctx, span := StartSpan(ctx, "PatchObject", objectAttributes(dryRun, gvk, obj.GetNamespace(), obj.GetName())...)
+	s.logger.Info("Patching item", "kind", gvk.Kind, "namespace", obj.GetNamespace(), "name", obj.GetName(), "dryRun", dryRun)
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	u.SetNamespace(obj.GetNamespace())
+	u.SetName(obj.GetName())
+	err := s.client.Patch(ctx, u, patch, opts...)
+	EndSpan(span, err)
+	return err
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+var _ = Describe("PatchService", func() {
+	var (
+		ctx           context.Context
+		c             client.Client
+		patchService  *PatchService
+		lookupService *LookupService
+		ns            *corev1.Namespace
+		pod1          *corev1.Pod
+		pod2          *corev1.Pod
+		podGVK        = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = testEnv.K8sClient
+
+		// Create test resources
+		t := testEnv.WithRandomSuffix()
+		ns = t.Namespace("patchservice")
+		pod1 = t.Pod("test-pod-1", ns.GetName())
+		pod2 = t.Pod("test-pod-2", ns.GetName())
+
+		Expect(c.Create(ctx, ns)).To(Succeed())
+		Expect(c.Create(ctx, pod1)).To(Succeed())
+		Expect(c.Create(ctx, pod2)).To(Succeed())
+
+		// Initialize services
+		lookupService = NewLookupService(ctx, c, t.Cfg, Options{Reader: c})
+		patchService = NewPatchService(ctx, c, lookupService, DenyList{})
+	})
+
+	Describe("PatchType", func() {
+		It("should default to a merge patch", func() {
+			pt, err := PatchType(cleanupv1alpha1.PreClusterDestroyCleanupPatch{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pt).To(Equal(types.MergePatchType))
+
+			pt, err = PatchType(cleanupv1alpha1.PreClusterDestroyCleanupPatch{Type: cleanupv1alpha1.PatchTypeJSON})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pt).To(Equal(types.JSONPatchType))
+
+			_, err = PatchType(cleanupv1alpha1.PreClusterDestroyCleanupPatch{Type: "strategic"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("PatchItem", func() {
+		It("should apply a merge patch to every matched object", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      "Pod",
+				Namespace: ns.GetName(),
+				Action:    cleanupv1alpha1.ActionPatch,
+				Patch: &cleanupv1alpha1.PreClusterDestroyCleanupPatch{
+					Data: apiextensionsv1.JSON{Raw: []byte(`{"metadata":{"labels":{"teardown":"true"}}}`)},
+				},
+			}
+
+			results, err := patchService.PatchItem(ctx, false, lookupTarget(ctx, podGVK, item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+
+			for _, pod := range []*corev1.Pod{pod1, pod2} {
+				p := &corev1.Pod{}
+				Expect(c.Get(ctx, client.ObjectKeyFromObject(pod), p)).To(Succeed())
+				Expect(p.GetLabels()).To(HaveKeyWithValue("teardown", "true"))
+			}
+			Expect(CountResults([]cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{{Objects: results}}, cleanupv1alpha1.ResultPatched)).To(Equal(2))
+		})
+
+		It("should apply a JSON patch to a named object", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      "Pod",
+				Namespace: ns.GetName(),
+				Name:      pod1.GetName(),
+				Action:    cleanupv1alpha1.ActionPatch,
+				Patch: &cleanupv1alpha1.PreClusterDestroyCleanupPatch{
+					Type: cleanupv1alpha1.PatchTypeJSON,
+					Data: apiextensionsv1.JSON{Raw: []byte(`[{"op":"add","path":"/metadata/annotations","value":{"teardown":"true"}}]`)},
+				},
+			}
+
+			results, err := patchService.PatchItem(ctx, false, lookupTarget(ctx, podGVK, item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Result).To(Equal(cleanupv1alpha1.ResultPatched))
+
+			p := &corev1.Pod{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pod1), p)).To(Succeed())
+			Expect(p.GetAnnotations()).To(HaveKeyWithValue("teardown", "true"))
+		})
+
+		It("should validate but not persist the patch in dry run mode", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      "Pod",
+				Namespace: ns.GetName(),
+				Name:      pod1.GetName(),
+				Action:    cleanupv1alpha1.ActionPatch,
+				Patch: &cleanupv1alpha1.PreClusterDestroyCleanupPatch{
+					Data: apiextensionsv1.JSON{Raw: []byte(`{"metadata":{"labels":{"teardown":"true"}}}`)},
+				},
+			}
+
+			results, err := patchService.PatchItem(ctx, true, lookupTarget(ctx, podGVK, item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Result).To(Equal(cleanupv1alpha1.ResultPatched))
+			Expect(results[0].Message).To(Equal(DryRunMessage))
+
+			p := &corev1.Pod{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pod1), p)).To(Succeed())
+			Expect(p.GetLabels()).NotTo(HaveKey("teardown"))
+
+			By("reporting patches rejected by the API server")
+			item.Patch.Data = apiextensionsv1.JSON{Raw: []byte(`{"spec":{"containers":null}}`)}
+			results, err = patchService.PatchItem(ctx, true, lookupTarget(ctx, podGVK, item), item)
+			Expect(err).To(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Result).To(Equal(cleanupv1alpha1.ResultFailed))
+		})
+
+		It("should return error when no patch is specified", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      "Pod",
+				Namespace: ns.GetName(),
+				Action:    cleanupv1alpha1.ActionPatch,
+			}
+
+			_, err := patchService.PatchItem(ctx, false, lookupTarget(ctx, podGVK, item), item)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
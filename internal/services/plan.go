@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+const (
+	PlanJSONKey  = "plan.json" // PlanJSONKey is the key of the machine-readable plan in the plan ConfigMap
+	PlanTableKey = "plan.txt"  // PlanTableKey is the key of the human-readable plan in the plan ConfigMap
+
+	// MaxPlanSize is the size above which a plan is saved as a summary, well below the 1 MiB limit of a ConfigMap.
+	MaxPlanSize = 768 * 1024
+)
+
+// Plan lists the objects a dry run of a PreClusterDestroyCleanup would act on, grouped by item.
+type Plan struct {
+	Name      string     `json:"name"`                // Name of the PreClusterDestroyCleanup
+	Namespace string     `json:"namespace"`           // Namespace of the PreClusterDestroyCleanup
+	Hash      string     `json:"hash"`                // Hash of the planned objects, to be approved with spec.approvedPlanHash
+	Items     []PlanItem `json:"items"`               // Items mirrors spec.resources
+	Truncated bool       `json:"truncated,omitempty"` // Truncated is true when the plan was too large to list its objects, see Summary
+	Stored    []string   `json:"stored,omitempty"`    // Stored names the ConfigMaps holding the full plan when it is truncated
+}
+
+// PlanItem lists the objects an entry in spec.resources would act on.
+type PlanItem struct {
+	Index   int                                                    `json:"index"`             // Index of the item in spec.resources
+	Kind    string                                                 `json:"kind,omitempty"`    // Kind resolved from the item
+	Action  string                                                 `json:"action,omitempty"`  // Action of the item
+	Phase   string                                                 `json:"phase,omitempty"`   // Phase the item belongs to
+	Message string                                                 `json:"message,omitempty"` // Message holds the error that prevented the item from being planned, if any
+	Count   int                                                    `json:"count"`             // Count of the objects matched by the item
+	Objects []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus `json:"objects"`           // Objects matched by the item, with what the API server said about the dry run
+}
+
+// NewPlan builds the plan of a PreClusterDestroyCleanup from the status of its items after a dry run.
+func NewPlan(obj *cleanupv1alpha1.PreClusterDestroyCleanup) Plan {
+	plan := Plan{
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+		Hash:      PlanHash(obj.Status.Items),
+		Items:     make([]PlanItem, 0, len(obj.Status.Items)),
+	}
+	for _, st := range obj.Status.Items {
+		objects := st.Objects
+		if objects == nil {
+			objects = []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{}
+		}
+		plan.Items = append(plan.Items, PlanItem{
+			Index:   st.Index,
+			Kind:    st.Kind,
+			Action:  st.Action,
+			Phase:   st.Phase,
+			Message: st.Message,
+			Count:   len(objects),
+			Objects: objects,
+		})
+	}
+	return plan
+}
+
+// PlanHash returns a hash of the objects planned by items, identifying each object by its item, identity, UID and result.
+// The hash does not depend on messages, or on the order in which the objects of an item were listed.
+func PlanHash(items []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) string {
+	lines := []string{}
+	for _, item := range items {
+		for _, o := range item.Objects {
+			lines = append(lines, fmt.Sprintf("%d|%s|%s|%s|%s|%s|%s|%s", item.Index, item.Action, o.APIVersion, o.Kind, o.Namespace, o.Name, o.UID, o.Result))
+		}
+	}
+	slices.Sort(lines)
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// approvedObjectsKey is the context key of the objects an item may act on, see WithApprovedObjects.
+type approvedObjectsKey struct{}
+
+// WithApprovedObjects returns a context limiting the actions performed with it to the objects with the given UIDs,
+// the objects of the approved plan of an item. Any other object is skipped, even if it is matched by the item.
+func WithApprovedObjects(ctx context.Context, uids []types.UID) context.Context {
+	approved := make(map[types.UID]bool, len(uids))
+	for _, uid := range uids {
+		approved[uid] = true
+	}
+	return context.WithValue(ctx, approvedObjectsKey{}, approved)
+}
+
+// NotApproved returns the reason an object must be skipped because it is not in the approved plan of the context,
+// or an empty string if it may be acted on, or the context does not limit the objects, see WithApprovedObjects.
+func NotApproved(ctx context.Context, obj metav1.Object) string {
+	approved, ok := ctx.Value(approvedObjectsKey{}).(map[types.UID]bool)
+	if !ok || approved[obj.GetUID()] {
+		return ""
+	}
+	return "not in the approved plan"
+}
+
+// ApprovedUIDs returns the UIDs of the objects planned by an item, to be recorded as its approved objects.
+func ApprovedUIDs(item cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) []types.UID {
+	uids := []types.UID{}
+	for _, o := range item.Objects {
+		if o.UID != "" {
+			uids = append(uids, o.UID)
+		}
+	}
+	return uids
+}
+
+// Summary returns a copy of the plan without the objects of its items, only their count, for plans too large to save.
+// The hash still covers every planned object, and stored names the ConfigMaps holding the full plan.
+func (p Plan) Summary(stored []string) Plan {
+	summary := p
+	summary.Truncated = true
+	summary.Stored = stored
+	summary.Items = make([]PlanItem, 0, len(p.Items))
+	for _, item := range p.Items {
+		item.Objects = []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{}
+		summary.Items = append(summary.Items, item)
+	}
+	return summary
+}
+
+// Table renders the plan as a human-readable table, with one row per object, after the hash of the plan.
+func (p Plan) Table() string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "Plan hash: %s\n\n", p.Hash)
+	if p.Truncated {
+		fmt.Fprint(sb, "The plan is too large to list its objects, only their number is shown.\n")
+		fmt.Fprintf(sb, "The full plan is stored as gzipped JSON, split across the ConfigMaps %s.\n\n", strings.Join(p.Stored, ", "))
+	}
+	w := tabwriter.NewWriter(sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ITEM\tPHASE\tACTION\tKIND\tNAMESPACE\tNAME\tRESULT\tMESSAGE")
+	for _, item := range p.Items {
+		if len(item.Objects) == 0 {
+			message := item.Message
+			if p.Truncated && message == "" {
+				message = fmt.Sprintf("%d object(s)", item.Count)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t\t\t\t%s\n", item.Index, item.Phase, item.Action, item.Kind, message)
+			continue
+		}
+		for _, o := range item.Objects {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", item.Index, item.Phase, item.Action, o.Kind, o.Namespace, o.Name, o.Result, o.Message)
+		}
+	}
+	_ = w.Flush()
+	return sb.String()
+}
+
+// PlanService provides methods to persist the plan of a dry run for review.
+type PlanService struct {
+	client client.Client
+	store  *StoreService
+	logger logr.Logger
+}
+
+// NewPlanService creates a new PlanService instance.
+// If opts.Reader is set, the plan ConfigMap is read with it rather than with the cache of the client,
+// which would hold every ConfigMap of the cluster in memory.
+func NewPlanService(ctx context.Context, client client.Client, opts Options) *PlanService {
+	store := NewStoreService(ctx, client, opts)
+	if opts.Reader != nil {
+		client = &uncachedClient{Client: client, reader: opts.Reader}
+	}
+	return &PlanService{
+		client: client,
+		store:  store,
+		logger: log.FromContext(ctx),
+	}
+}
+
+// PlanName returns the name of the ConfigMap holding the plan of a PreClusterDestroyCleanup.
+func PlanName(obj *cleanupv1alpha1.PreClusterDestroyCleanup) string {
+	return obj.GetName() + "-plan"
+}
+
+// FullPlanName returns the key under which the full plan of a PreClusterDestroyCleanup is stored when it is truncated.
+func FullPlanName(obj *cleanupv1alpha1.PreClusterDestroyCleanup) string {
+	return PlanName(obj) + "-full"
+}
+
+// SavePlan writes the plan of a PreClusterDestroyCleanup, as JSON and as a table, to a ConfigMap next to it.
+// The ConfigMap is owned by the PreClusterDestroyCleanup, so that it is garbage collected with it.
+// Plans larger than MaxPlanSize are saved as their Summary, so that they still fit in the ConfigMap,
+// and the full plan is stored as gzipped JSON under FullPlanName with a StoreService.
+// It returns a reference to the ConfigMap, whether the plan was truncated, and any errors encountered while writing it.
+func (s *PlanService) SavePlan(ctx context.Context, obj *cleanupv1alpha1.PreClusterDestroyCleanup, plan Plan) (*corev1.LocalObjectReference, bool, error) {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	table := plan.Table()
+	if size := len(data) + len(table); size > MaxPlanSize {
+		s.logger.Info("Plan too large, saving a summary", "namespace", obj.GetNamespace(), "name", obj.GetName(), "size", size, "maxSize", MaxPlanSize)
+		stored, err := s.store.Save(ctx, obj, FullPlanName(obj), data)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to store full plan: %w", err)
+		}
+		plan = plan.Summary(stored)
+		if data, err = json.MarshalIndent(plan, "", "  "); err != nil {
+			return nil, false, fmt.Errorf("failed to marshal plan: %w", err)
+		}
+		table = plan.Table()
+	} else if err := s.store.Delete(ctx, obj.GetNamespace(), FullPlanName(obj)); err != nil {
+		return nil, false, fmt.Errorf("failed to delete stale full plan: %w", err)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: obj.GetNamespace(), Name: PlanName(obj)}}
+	op, err := controllerutil.CreateOrUpdate(ctx, s.client, cm, func() error {
+		cm.Data = map[string]string{
+			PlanJSONKey:  string(data),
+			PlanTableKey: table,
+		}
+		return controllerutil.SetControllerReference(obj, cm, s.client.Scheme())
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to save plan %s/%s: %w", cm.GetNamespace(), cm.GetName(), err)
+	}
+
+	s.logger.Info("Saved plan", "namespace", cm.GetNamespace(), "name", cm.GetName(), "operation", op, "truncated", plan.Truncated)
+	return &corev1.LocalObjectReference{Name: cm.GetName()}, plan.Truncated, nil
+}
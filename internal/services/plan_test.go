@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+var _ = Describe("PlanService", func() {
+	var (
+		ctx         context.Context
+		c           client.Client
+		planService *PlanService
+		ns          *corev1.Namespace
+		obj         *cleanupv1alpha1.PreClusterDestroyCleanup
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = testEnv.K8sClient
+
+		t := testEnv.WithRandomSuffix()
+		ns = t.Namespace("planservice")
+		obj = &cleanupv1alpha1.PreClusterDestroyCleanup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      t.FormatName("test-cleanup"),
+				Namespace: ns.GetName(),
+			},
+			Spec: cleanupv1alpha1.PreClusterDestroyCleanupSpec{DryRun: true},
+		}
+
+		Expect(c.Create(ctx, ns)).To(Succeed())
+		Expect(c.Create(ctx, obj)).To(Succeed())
+
+		obj.Status.Items = []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{
+			{
+				Index:  0,
+				Kind:   "Deployment",
+				Action: cleanupv1alpha1.ActionScaleToZero,
+				Objects: []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{
+					{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "apps", Name: "web", Result: cleanupv1alpha1.ResultScaled, Message: DryRunMessage},
+				},
+			},
+			{
+				Index:   1,
+				Kind:    "Pod",
+				Action:  cleanupv1alpha1.ActionDelete,
+				Message: "no resources found",
+			},
+		}
+
+		planService = NewPlanService(ctx, c, Options{Reader: c})
+	})
+
+	Describe("NewPlan", func() {
+		It("should group the objects by item", func() {
+			plan := NewPlan(obj)
+			Expect(plan.Name).To(Equal(obj.GetName()))
+			Expect(plan.Items).To(HaveLen(2))
+			Expect(plan.Items[0].Objects).To(HaveLen(1))
+			Expect(plan.Items[0].Objects[0].Name).To(Equal("web"))
+			Expect(plan.Items[1].Objects).To(BeEmpty())
+		})
+
+		It("should hash the planned objects regardless of their order and messages", func() {
+			plan := NewPlan(obj)
+			Expect(plan.Hash).To(Equal(PlanHash(obj.Status.Items)))
+
+			obj.Status.Items[0].Objects = append(obj.Status.Items[0].Objects, cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{
+				APIVersion: "apps/v1", Kind: "Deployment", Namespace: "apps", Name: "api", Result: cleanupv1alpha1.ResultScaled,
+			})
+			hash := PlanHash(obj.Status.Items)
+			Expect(hash).NotTo(Equal(plan.Hash))
+
+			objects := obj.Status.Items[0].Objects
+			objects[0], objects[1] = objects[1], objects[0]
+			objects[0].Message = "changed"
+			Expect(PlanHash(obj.Status.Items)).To(Equal(hash))
+		})
+
+		It("should render a table with one row per object", func() {
+			table := NewPlan(obj).Table()
+			Expect(table).To(ContainSubstring("NAMESPACE"))
+			Expect(table).To(MatchRegexp(`0\s+scaleToZero\s+Deployment\s+apps\s+web\s+Scaled`))
+			Expect(table).To(ContainSubstring("no resources found"))
+		})
+	})
+
+	Describe("SavePlan", func() {
+		It("should write the plan to a ConfigMap owned by the cleanup", func() {
+			ref, truncated, err := planService.SavePlan(ctx, obj, NewPlan(obj))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(truncated).To(BeFalse())
+			Expect(ref.Name).To(Equal(PlanName(obj)))
+
+			cm := &corev1.ConfigMap{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: ns.GetName(), Name: ref.Name}, cm)).To(Succeed())
+			Expect(metav1.IsControlledBy(cm, obj)).To(BeTrue())
+			Expect(cm.Data).To(HaveKey(PlanTableKey))
+
+			plan := Plan{}
+			Expect(json.Unmarshal([]byte(cm.Data[PlanJSONKey]), &plan)).To(Succeed())
+			Expect(plan.Items).To(HaveLen(2))
+		})
+
+		It("should update an existing plan", func() {
+			_, _, err := planService.SavePlan(ctx, obj, NewPlan(obj))
+			Expect(err).NotTo(HaveOccurred())
+
+			obj.Status.Items = obj.Status.Items[:1]
+			ref, _, err := planService.SavePlan(ctx, obj, NewPlan(obj))
+			Expect(err).NotTo(HaveOccurred())
+
+			cm := &corev1.ConfigMap{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: ns.GetName(), Name: ref.Name}, cm)).To(Succeed())
+			Expect(cm.Data[PlanTableKey]).NotTo(ContainSubstring("no resources found"))
+		})
+
+		It("should save a summary of plans too large for a ConfigMap", func() {
+			obj.Status.Items[1].Message = ""
+			for i := range 20000 {
+				obj.Status.Items[1].Objects = append(obj.Status.Items[1].Objects, cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{
+					APIVersion: "v1", Kind: "Pod", Namespace: "apps", Name: fmt.Sprintf("pod-%d", i), Result: cleanupv1alpha1.ResultDeleted,
+				})
+			}
+			full := NewPlan(obj)
+
+			ref, truncated, err := planService.SavePlan(ctx, obj, full)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(truncated).To(BeTrue())
+
+			cm := &corev1.ConfigMap{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: ns.GetName(), Name: ref.Name}, cm)).To(Succeed())
+			Expect(len(cm.Data[PlanJSONKey]) + len(cm.Data[PlanTableKey])).To(BeNumerically("<=", MaxPlanSize))
+			Expect(cm.Data[PlanTableKey]).To(ContainSubstring(full.Hash))
+			Expect(cm.Data[PlanTableKey]).To(MatchRegexp(`1\s+delete\s+Pod\s+20000 object\(s\)`))
+
+			plan := Plan{}
+			Expect(json.Unmarshal([]byte(cm.Data[PlanJSONKey]), &plan)).To(Succeed())
+			Expect(plan.Truncated).To(BeTrue())
+			Expect(plan.Hash).To(Equal(full.Hash))
+			Expect(plan.Items[1].Count).To(Equal(20000))
+			Expect(plan.Items[1].Objects).To(BeEmpty())
+			Expect(plan.Stored).To(Equal([]string{ChunkName(FullPlanName(obj), 0)}))
+			Expect(cm.Data[PlanTableKey]).To(ContainSubstring(ChunkName(FullPlanName(obj), 0)))
+
+			data, err := NewStoreService(ctx, c, Options{Reader: c}).Load(ctx, ns.GetName(), FullPlanName(obj))
+			Expect(err).NotTo(HaveOccurred())
+			stored := Plan{}
+			Expect(json.Unmarshal(data, &stored)).To(Succeed())
+			Expect(stored.Truncated).To(BeFalse())
+			Expect(stored.Items[1].Objects).To(HaveLen(20000))
+		})
+
+		It("should delete the full plan once the plan fits in its ConfigMap", func() {
+			store := NewStoreService(ctx, c, Options{Reader: c})
+			_, err := store.Save(ctx, obj, FullPlanName(obj), []byte("{}"))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, truncated, err := planService.SavePlan(ctx, obj, NewPlan(obj))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(truncated).To(BeFalse())
+
+			data, err := store.Load(ctx, ns.GetName(), FullPlanName(obj))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+// DenyList holds the namespaces and kinds that can never be touched by a cleanup, regardless of its items.
+// Kinds are given as "Kind" to match the kind in any group, or "Kind.group" to match a single group.
+// Every service acting on objects is given the deny list, and never deletes, scales, suspends or patches the objects it protects.
+type DenyList struct {
+	Namespaces []string
+	Kinds      []string
+}
+
+// Protection decides whether an object must be skipped, based on the deny list, the protect annotation
+// and the exclusions of an item. Skipped objects are not acted on, but reported with their reason, see SkippedObjectStatus.
+type Protection struct {
+	deny     DenyList
+	exclude  *cleanupv1alpha1.PreClusterDestroyCleanupExclude
+	selector labels.Selector
+}
+
+// NewProtection creates a Protection for the deny list and the exclusions of an item, which may be nil.
+// It returns an error if the label selector of the exclusions cannot be parsed.
+func NewProtection(deny DenyList, exclude *cleanupv1alpha1.PreClusterDestroyCleanupExclude) (*Protection, error) {
+	p := &Protection{deny: deny, exclude: exclude}
+	if exclude != nil && exclude.LabelSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(exclude.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude label selector: %w", err)
+		}
+		p.selector = selector
+	}
+	return p, nil
+}
+
+// SkipReason returns the reason an object must be skipped, or an empty string if it may be touched.
+func (p *Protection) SkipReason(gvk schema.GroupVersionKind, obj metav1.Object) string {
+	ns := obj.GetNamespace()
+	// namespaces on the deny list protect the namespace object itself as well as its contents
+	if gvk.Group == "" && gvk.Kind == "Namespace" {
+		ns = obj.GetName()
+	}
+
+	if ns != "" && slices.Contains(p.deny.Namespaces, ns) {
+		return fmt.Sprintf("namespace %s is on the deny list", ns)
+	}
+
+	if slices.ContainsFunc(p.deny.Kinds, func(k string) bool { return kindMatches(k, gvk) }) {
+		return fmt.Sprintf("kind %s is on the deny list", gvk.GroupKind())
+	}
+
+	if strings.EqualFold(obj.GetAnnotations()[cleanupv1alpha1.ProtectAnnotation], "true") {
+		return fmt.Sprintf("protected by annotation %s", cleanupv1alpha1.ProtectAnnotation)
+	}
+
+	if p.exclude == nil {
+		return ""
+	}
+
+	if obj.GetNamespace() != "" && slices.Contains(p.exclude.Namespaces, obj.GetNamespace()) {
+		return fmt.Sprintf("namespace %s is excluded", obj.GetNamespace())
+	}
+
+	if slices.Contains(p.exclude.Names, obj.GetName()) {
+		return fmt.Sprintf("name %s is excluded", obj.GetName())
+	}
+
+	if p.selector != nil && p.selector.Matches(labels.Set(obj.GetLabels())) {
+		return fmt.Sprintf("labels match exclude selector %s", p.selector)
+	}
+
+	return ""
+}
+
+// kindMatches reports whether a deny list entry, "Kind" or "Kind.group", matches a GroupVersionKind.
+func kindMatches(entry string, gvk schema.GroupVersionKind) bool {
+	gk := schema.ParseGroupKind(entry)
+	if !strings.EqualFold(gk.Kind, gvk.Kind) {
+		return false
+	}
+	return gk.Group == "" || strings.EqualFold(gk.Group, gvk.Group)
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+var _ = Describe("Protection", func() {
+	var (
+		podGVK = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+		pod    *corev1.Pod
+	)
+
+	BeforeEach(func() {
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "apps",
+				Name:      "web",
+				Labels:    map[string]string{"app": "web"},
+			},
+		}
+	})
+
+	Describe("SkipReason", func() {
+		It("should allow objects that are not protected", func() {
+			p, err := NewProtection(DenyList{Namespaces: []string{"kube-system"}, Kinds: []string{"Secret"}}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.SkipReason(podGVK, pod)).To(BeEmpty())
+		})
+
+		It("should skip objects in namespaces on the deny list", func() {
+			p, err := NewProtection(DenyList{Namespaces: []string{"apps"}}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.SkipReason(podGVK, pod)).To(ContainSubstring("namespace apps is on the deny list"))
+
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+			Expect(p.SkipReason(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, ns)).To(ContainSubstring("on the deny list"))
+		})
+
+		It("should skip kinds on the deny list with or without a group", func() {
+			p, err := NewProtection(DenyList{Kinds: []string{"pod"}}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.SkipReason(podGVK, pod)).To(ContainSubstring("is on the deny list"))
+
+			p, err = NewProtection(DenyList{Kinds: []string{"Deployment.apps"}}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.SkipReason(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, pod)).NotTo(BeEmpty())
+			Expect(p.SkipReason(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Deployment"}, pod)).To(BeEmpty())
+		})
+
+		It("should skip objects with the protect annotation", func() {
+			pod.SetAnnotations(map[string]string{cleanupv1alpha1.ProtectAnnotation: "true"})
+			p, err := NewProtection(DenyList{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.SkipReason(podGVK, pod)).To(ContainSubstring(cleanupv1alpha1.ProtectAnnotation))
+		})
+
+		It("should skip objects matching the exclusions of an item", func() {
+			p, err := NewProtection(DenyList{}, &cleanupv1alpha1.PreClusterDestroyCleanupExclude{Namespaces: []string{"apps"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.SkipReason(podGVK, pod)).To(ContainSubstring("namespace apps is excluded"))
+
+			p, err = NewProtection(DenyList{}, &cleanupv1alpha1.PreClusterDestroyCleanupExclude{Names: []string{"web"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.SkipReason(podGVK, pod)).To(ContainSubstring("name web is excluded"))
+
+			p, err = NewProtection(DenyList{}, &cleanupv1alpha1.PreClusterDestroyCleanupExclude{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.SkipReason(podGVK, pod)).To(ContainSubstring("exclude selector"))
+		})
+
+		It("should return an error for an invalid exclude selector", func() {
+			_, err := NewProtection(DenyList{}, &cleanupv1alpha1.PreClusterDestroyCleanupExclude{
+				LabelSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Bogus"}},
+				},
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
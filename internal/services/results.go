@@ -0,0 +1,113 @@
+package services
+
+import (
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+// NewObjectStatus builds the status entry recording the result of an action on an object.
+// If err is not nil, the result is recorded as failed and the error is used as the message.
+func NewObjectStatus(gvk schema.GroupVersionKind, obj client.Object, result string, err error) cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus {
+	status := cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		UID:        obj.GetUID(),
+		Result:     result,
+	}
+	if err != nil {
+		status.Result = cleanupv1alpha1.ResultFailed
+		status.Message = err.Error()
+	}
+	return status
+}
+
+// DryRunMessage is the message recorded for objects whose dry run request was accepted by the API server.
+const DryRunMessage = "Dry run accepted by the API server"
+
+// ObjectStatus builds the status entry recording the result of an action on an object, like NewObjectStatus.
+// If dryRun is true and err is nil, the action was sent with DryRunAll and accepted, and DryRunMessage is used as the message.
+func ObjectStatus(dryRun bool, gvk schema.GroupVersionKind, obj client.Object, result string, err error) cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus {
+	status := NewObjectStatus(gvk, obj, result, err)
+	if dryRun && err == nil {
+		status.Message = DryRunMessage
+	}
+	return status
+}
+
+// SkippedObjectStatus builds the status entry for an object that was skipped, with the reason as the message.
+func SkippedObjectStatus(gvk schema.GroupVersionKind, obj client.Object, reason string) cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus {
+	status := NewObjectStatus(gvk, obj, cleanupv1alpha1.ResultSkipped, nil)
+	status.Message = reason
+	return status
+}
+
+// CountResults returns the number of objects across all items that have one of the given results.
+func CountResults(items []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus, results ...string) int {
+	count := 0
+	for _, item := range items {
+		for _, o := range item.Objects {
+			if slices.Contains(results, o.Result) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// ResultCounts returns the number of objects by result, or nil if there are no objects.
+func ResultCounts(objects []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) map[string]int {
+	if len(objects) == 0 {
+		return nil
+	}
+	counts := map[string]int{}
+	for _, o := range objects {
+		counts[o.Result]++
+	}
+	return counts
+}
+
+// CountItems returns the number of items in one of the given states.
+func CountItems(items []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus, states ...string) int {
+	count := 0
+	for _, item := range items {
+		if slices.Contains(states, item.State) {
+			count++
+		}
+	}
+	return count
+}
+
+// CountRetrying returns the number of failed items waiting to be retried.
+func CountRetrying(items []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) int {
+	count := 0
+	for _, item := range items {
+		if item.State == cleanupv1alpha1.ItemStatePending && item.NextRetryTime != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// NextRetry returns the earliest time at which a failed item waiting to be retried is retried, or nil if none is.
+func NextRetry(items []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) *metav1.Time {
+	var next *metav1.Time
+	for _, item := range items {
+		if item.State == cleanupv1alpha1.ItemStatePending && item.NextRetryTime != nil && (next == nil || item.NextRetryTime.Before(next)) {
+			next = item.NextRetryTime
+		}
+	}
+	return next
+}
+
+// CountProcessed returns the number of objects across all items that were deleted, scaled, suspended or patched.
+func CountProcessed(items []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) int {
+	return CountResults(items, cleanupv1alpha1.ResultDeleted, cleanupv1alpha1.ResultForced, cleanupv1alpha1.ResultScaled,
+		cleanupv1alpha1.ResultSuspended, cleanupv1alpha1.ResultPatched)
+}
@@ -2,136 +2,322 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 
 	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
 	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/scale"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-// ScaleService provides methods to scale resources like Deployments and StatefulSets.
+// ScaleService provides methods to scale resources that expose a scale subresource, like Deployments and StatefulSets.
 type ScaleService struct {
 	client client.Client
 	lookup *LookupService
+	scales scale.ScalesGetter
+	deny   DenyList
 	logger logr.Logger
 }
 
 // NewScaleService creates a new ScaleService instance.
-func NewScaleService(ctx context.Context, client client.Client, lookup *LookupService) *ScaleService {
+func NewScaleService(ctx context.Context, client client.Client, lookup *LookupService, deny DenyList) *ScaleService {
 	return &ScaleService{
 		client: client,
 		lookup: lookup,
+		deny:   deny,
 		logger: log.FromContext(ctx),
 	}
 }
 
-// ScaleItem scales a resource to specified replicas if it is a Deployment or StatefulSet.
-// It returns the count of scaled resources (1 if successful, 0 if not applicable) and any errors encountered during scaling.
-// If dryRun is true, it only logs the action without actually scaling the resource.
-func (s *ScaleService) ScaleItem(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, item cleanupv1alpha1.PreClusterDestroyCleanupItem, replicas *int32) (int, error) {
-	if gvk.Kind != DeploymentKind && gvk.Kind != StatefulSetKind {
-		return 0, fmt.Errorf("scaling is not supported for kind %s", gvk.Kind)
+// ScaleItem scales the objects of the target of a PreClusterDestroyCleanupItem to specified replicas, see LookupTarget,
+// if their kind exposes a scale subresource.
+// It returns the status of each object touched and any errors encountered during scaling.
+// If dryRun is true, the requests are sent with DryRunAll so the API server validates them without scaling the resource.
+func (s *ScaleService) ScaleItem(ctx context.Context, dryRun bool, target Target, item cleanupv1alpha1.PreClusterDestroyCleanupItem, replicas *int32) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	kinds := make([]schema.GroupVersionKind, 0, len(target.Kinds))
+	for _, gvk := range target.Kinds {
+		mapping, err := s.lookup.LookupScaleResource(gvk)
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, mapping.GroupVersionKind)
 	}
+	target.Kinds = kinds
 
-	if item.Name != "" {
-		c, err := s.ScaleKind(ctx, dryRun, gvk, item.Namespace, item.Name, replicas)
+	return s.lookup.ForEachObject(ctx, target, item, s.deny, func(ctx context.Context, gvk schema.GroupVersionKind, obj *metav1.PartialObjectMetadata) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+		result, err := s.scaleKind(ctx, dryRun, gvk, obj.GetNamespace(), obj.GetName(), replicas, &Protection{deny: s.deny})
 		if err != nil {
-			return 0, fmt.Errorf("failed to scale %s/%s: %w", item.Namespace, item.Name, err)
+			err = fmt.Errorf("failed to scale %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
 		}
-		return c, nil
+		return result, err
+	})
+}
+
+// ScaleKind scales a named resource of any kind that exposes a scale subresource to specified replicas.
+// The previous replica count is recorded in an annotation so that it can be restored by RestoreObject.
+// It returns the status of the resource and any errors encountered during scaling.
+// If dryRun is true, the requests are sent with DryRunAll so the API server validates them without scaling the resource.
+func (s *ScaleService) ScaleKind(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, ns string, name string, replicas *int32) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	return s.scaleKind(ctx, dryRun, gvk, ns, name, replicas, &Protection{deny: s.deny})
+}
+
+// scaleKind scales a named resource to specified replicas through its scale subresource, unless it is protected by protection.
+// Each resource is scaled in a span of its own.
+func (s *ScaleService) scaleKind(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, ns string, name string, replicas *int32, protection *Protection) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	ctx, span := StartSpan(ctx, "ScaleObject", append(objectAttributes(dryRun, gvk, ns, name), attribute.Int("quartz.replicas", int(*replicas)))...)
+	result, err := s.scaleObject(ctx, dryRun, gvk, ns, name, replicas, protection)
+	span.SetAttributes(attribute.String("quartz.result", result.Result))
+	EndSpan(span, err)
+	return result, err
+}
+
+// scaleObject scales a named resource to specified replicas through its scale subresource, unless it is protected by protection.
+func (s *ScaleService) scaleObject(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, ns string, name string, replicas *int32, protection *Protection) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	item := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+	if name == "" {
+		s.logger.Info("No name specified for scaling, skipping")
+		return NewObjectStatus(gvk, item, cleanupv1alpha1.ResultSkipped, nil), nil // Nothing to scale
 	}
 
-	// lookup all resources of the specified kind in the namespace
-	list, err := s.lookup.ListResources(ctx, gvk, item.Namespace)
+	mapping, err := s.lookup.LookupScaleResource(gvk)
 	if err != nil {
-		return 0, fmt.Errorf("failed to list resources of kind %s in namespace %s: %w", gvk.Kind, item.Namespace, err)
+		return NewObjectStatus(gvk, item, cleanupv1alpha1.ResultFailed, err), err
 	}
+	gvk = mapping.GroupVersionKind
+	item.SetGroupVersionKind(gvk)
 
-	if len(list.Items) == 0 {
-		s.logger.Info("No resources found to scale", "kind", gvk.Kind, "namespace", item.Namespace)
-		return 0, nil // Nothing to scale
+	if err := s.client.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, item); err != nil {
+		err = fmt.Errorf("failed to get %s/%s: %w", ns, name, err)
+		return NewObjectStatus(gvk, item, cleanupv1alpha1.ResultFailed, err), err
 	}
 
-	count := 0
-	errs := []error{}
-	for _, i := range list.Items {
-		c, err := s.ScaleKind(ctx, dryRun, gvk, i.GetNamespace(), i.GetName(), replicas)
-		if err != nil {
-			errs = append(errs, fmt.Errorf("failed to scale %s/%s: %w", i.GetNamespace(), i.GetName(), err))
-		}
-		count += c
+	if reason := protection.SkipReason(gvk, item); reason != "" {
+		s.logger.Info("Skipping protected item", "kind", gvk.Kind, "namespace", ns, "name", name, "reason", reason)
+		return SkippedObjectStatus(gvk, item, reason), nil
 	}
 
-	return count, errors.Join(errs...)
-}
+	scales, err := s.scaleClient()
+	if err != nil {
+		return NewObjectStatus(gvk, item, cleanupv1alpha1.ResultFailed, err), err
+	}
 
-func (r *ScaleService) ScaleKind(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, ns string, name string, replicas *int32) (int, error) {
-	switch gvk.Kind {
-	case DeploymentKind:
-		return r.ScaleDeployment(ctx, dryRun, ns, name, replicas)
-	case StatefulSetKind:
-		return r.ScaleStatefulSet(ctx, dryRun, ns, name, replicas)
-	default:
-		return 0, fmt.Errorf("replica scaling is not supported for kind %s", gvk.Kind)
+	current, err := scales.Scales(ns).Get(ctx, mapping.Resource.GroupResource(), name, metav1.GetOptions{})
+	if err != nil {
+		err = fmt.Errorf("failed to get scale of %s/%s: %w", ns, name, err)
+		return NewObjectStatus(gvk, item, cleanupv1alpha1.ResultFailed, err), err
+	}
+
+	// record the previous replica count first, so that it is never lost once the resource has been scaled
+	if current.Spec.Replicas != *replicas {
+		if err := s.annotatePreviousReplicas(ctx, dryRun, item, strconv.FormatInt(int64(current.Spec.Replicas), 10)); err != nil {
+			err = fmt.Errorf("failed to record previous replicas of %s/%s: %w", ns, name, err)
+			return NewObjectStatus(gvk, item, cleanupv1alpha1.ResultFailed, err), err
+		}
 	}
+
+	s.logger.Info("Scaling resource", "kind", gvk.Kind, "namespace", ns, "name", name, "replicas", *replicas, "dryRun", dryRun)
+	current.Spec.Replicas = *replicas
+	if _, err := scales.Scales(ns).Update(ctx, mapping.Resource.GroupResource(), current, updateOptions(dryRun)); err != nil {
+		err = fmt.Errorf("failed to scale %s/%s: %w", ns, name, err)
+		return NewObjectStatus(gvk, item, cleanupv1alpha1.ResultFailed, err), err
+	}
+
+	s.logger.Info("Scaled resource", "kind", gvk.Kind, "namespace", ns, "name", name, "replicas", *replicas, "dryRun", dryRun)
+	return ObjectStatus(dryRun, gvk, item, cleanupv1alpha1.ResultScaled, nil), nil
 }
 
 // ScaleDeployment scales a Deployment to specified replicas.
-// It returns the count of scaled resources (1 if successful, 0 if not applicable) and any errors encountered during scaling.
-// If dryRun is true, it only logs the action without actually scaling the resource.
-func (s *ScaleService) ScaleDeployment(ctx context.Context, dryRun bool, ns string, name string, replicas *int32) (int, error) {
-	if name == "" {
-		s.logger.Info("No name specified for scaling, skipping")
-		return 0, nil // Nothing to scale
+// It returns the status of the deployment and any errors encountered during scaling.
+// If dryRun is true, the requests are sent with DryRunAll so the API server validates them without scaling the resource.
+func (s *ScaleService) ScaleDeployment(ctx context.Context, dryRun bool, ns string, name string, replicas *int32) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	return s.ScaleKind(ctx, dryRun, appsv1.SchemeGroupVersion.WithKind(DeploymentKind), ns, name, replicas)
+}
+
+// ScaleStatefulSet scales a StatefulSet to specified replicas.
+func (s *ScaleService) ScaleStatefulSet(ctx context.Context, dryRun bool, ns string, name string, replicas *int32) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	return s.ScaleKind(ctx, dryRun, appsv1.SchemeGroupVersion.WithKind(StatefulSetKind), ns, name, replicas)
+}
+
+// annotatePreviousReplicas sets the previous replicas annotation of an object, or removes it if value is empty.
+// If dryRun is true, the patch is sent with DryRunAll and the annotation is left unchanged.
+func (s *ScaleService) annotatePreviousReplicas(ctx context.Context, dryRun bool, item *metav1.PartialObjectMetadata, value string) error {
+	var annotation any
+	if value != "" {
+		annotation = value
 	}
 
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]any{cleanupv1alpha1.PreviousReplicasAnnotation: annotation},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	opts := []client.PatchOption{}
 	if dryRun {
-		s.logger.Info("Dry run mode, skipping scaling", "kind", DeploymentKind, "namespace", ns, "name", name, "replicas", *replicas)
-		return 1, nil
+		opts = append(opts, client.DryRunAll)
 	}
+	return s.client.Patch(ctx, item, client.RawPatch(types.MergePatchType, patch), opts...)
+}
 
-	s.logger.Info("Scaling deployment", "kind", DeploymentKind, "namespace", ns, "name", name, "replicas", *replicas)
-	deployment := &appsv1.Deployment{}
-	if err := s.client.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, deployment); err != nil {
-		return 0, fmt.Errorf("failed to get %s/%s: %w", ns, name, err)
+// updateOptions returns the options for an update of a scale subresource, sending it with DryRunAll if dryRun is true.
+func updateOptions(dryRun bool) metav1.UpdateOptions {
+	if dryRun {
+		return metav1.UpdateOptions{DryRun: []string{metav1.DryRunAll}}
 	}
+	return metav1.UpdateOptions{}
+}
 
-	deployment.Spec.Replicas = replicas
-	if err := s.client.Update(ctx, deployment); err != nil {
-		return 0, fmt.Errorf("failed to scale %s/%s: %w", ns, name, err)
+// scaleClient returns the client for scale subresources, created on first use.
+func (s *ScaleService) scaleClient() (scale.ScalesGetter, error) {
+	if s.scales == nil {
+		scales, err := s.lookup.ScalesGetter()
+		if err != nil {
+			return nil, err
+		}
+		s.scales = scales
 	}
+	return s.scales, nil
+}
 
-	s.logger.Info("Scaled deployment", "kind", DeploymentKind, "namespace", ns, "name", name, "replicas", *replicas)
-	return 1, nil // Indicate that we scaled
+// RestoreObject scales a resource that was scaled by a cleanup back to the replica count recorded in its annotation,
+// and removes the annotation. Objects that no longer exist, were recreated, or have no recorded count are skipped.
+// If dryRun is true, the requests are sent with DryRunAll so the API server validates them without scaling the resource.
+func (s *ScaleService) RestoreObject(ctx context.Context, dryRun bool, o cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	ctx, span := StartSpan(ctx, "RestoreObject", objectAttributes(dryRun, schema.FromAPIVersionAndKind(o.APIVersion, o.Kind), o.Namespace, o.Name)...)
+	restored, err := s.restoreObject(ctx, dryRun, o)
+	span.SetAttributes(attribute.String("quartz.result", restored.Result))
+	EndSpan(span, err)
+	return restored, err
 }
 
-// ScaleStatefulSet scales a StatefulSet to specified replicas.
-func (s *ScaleService) ScaleStatefulSet(ctx context.Context, dryRun bool, ns string, name string, replicas *int32) (int, error) {
-	if name == "" {
-		s.logger.Info("No name specified for scaling, skipping")
-		return 0, nil // Nothing to scale
+// restoreObject scales a resource that was scaled by a cleanup back to the replica count recorded in its annotation.
+func (s *ScaleService) restoreObject(ctx context.Context, dryRun bool, o cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	restored := o
+	restored.Message = ""
+	failed := func(err error) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+		restored.Result = cleanupv1alpha1.ResultFailed
+		restored.Message = err.Error()
+		return restored, err
+	}
+
+	gvk := schema.FromAPIVersionAndKind(o.APIVersion, o.Kind)
+	item := &metav1.PartialObjectMetadata{}
+	item.SetGroupVersionKind(gvk)
+	if err := s.client.Get(ctx, client.ObjectKey{Namespace: o.Namespace, Name: o.Name}, item); err != nil {
+		if apierrors.IsNotFound(err) {
+			restored.Result = cleanupv1alpha1.ResultSkipped
+			restored.Message = "object no longer exists"
+			return restored, nil
+		}
+		return failed(fmt.Errorf("failed to get %s/%s: %w", o.Namespace, o.Name, err))
+	}
+
+	if o.UID != "" && item.GetUID() != o.UID {
+		restored.Result = cleanupv1alpha1.ResultSkipped
+		restored.Message = "object was recreated"
+		return restored, nil
+	}
+
+	value, ok := item.GetAnnotations()[cleanupv1alpha1.PreviousReplicasAnnotation]
+	if !ok {
+		restored.Result = cleanupv1alpha1.ResultSkipped
+		restored.Message = "no previous replica count recorded"
+		return restored, nil
+	}
+
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return failed(fmt.Errorf("invalid previous replica count %q on %s/%s: %w", value, o.Namespace, o.Name, err))
+	}
+
+	mapping, err := s.lookup.LookupScaleResource(gvk)
+	if err != nil {
+		return failed(err)
 	}
 
+	scales, err := s.scaleClient()
+	if err != nil {
+		return failed(err)
+	}
+
+	current, err := scales.Scales(o.Namespace).Get(ctx, mapping.Resource.GroupResource(), o.Name, metav1.GetOptions{})
+	if err != nil {
+		return failed(fmt.Errorf("failed to get scale of %s/%s: %w", o.Namespace, o.Name, err))
+	}
+
+	s.logger.Info("Restoring replicas", "kind", o.Kind, "namespace", o.Namespace, "name", o.Name, "replicas", replicas, "dryRun", dryRun)
+	current.Spec.Replicas = int32(replicas)
+	if _, err := scales.Scales(o.Namespace).Update(ctx, mapping.Resource.GroupResource(), current, updateOptions(dryRun)); err != nil {
+		return failed(fmt.Errorf("failed to restore %s/%s: %w", o.Namespace, o.Name, err))
+	}
+
+	if err := s.annotatePreviousReplicas(ctx, dryRun, item, ""); err != nil {
+		return failed(fmt.Errorf("failed to remove previous replicas of %s/%s: %w", o.Namespace, o.Name, err))
+	}
+
+	restored.Result = cleanupv1alpha1.ResultRestored
 	if dryRun {
-		s.logger.Info("Dry run mode, skipping scaling", "kind", StatefulSetKind, "namespace", ns, "name", name, "replicas", *replicas)
-		return 1, nil
+		restored.Message = DryRunMessage
 	}
+	return restored, nil
+}
+
+// PendingScale returns the scaled objects that still report running replicas in the status of their scale subresource.
+// Objects that no longer exist are considered scaled down.
+func (s *ScaleService) PendingScale(ctx context.Context, objects []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	ctx, span := StartSpan(ctx, "PendingScale", attribute.Int("quartz.objects", len(objects)))
+	pending, err := s.pendingScale(ctx, objects)
+	span.SetAttributes(attribute.Int("quartz.pending", len(pending)))
+	EndSpan(span, err)
+	return pending, err
+}
 
-	s.logger.Info("Scaling statefulset", "kind", StatefulSetKind, "namespace", ns, "name", name, "replicas", *replicas)
-	statefulSet := &appsv1.StatefulSet{}
-	if err := s.client.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, statefulSet); err != nil {
-		return 0, fmt.Errorf("failed to get %s/%s: %w", ns, name, err)
+// pendingScale returns the scaled objects that still report running replicas.
+func (s *ScaleService) pendingScale(ctx context.Context, objects []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	scales, err := s.scaleClient()
+	if err != nil {
+		return nil, err
 	}
 
-	statefulSet.Spec.Replicas = replicas
-	if err := s.client.Update(ctx, statefulSet); err != nil {
-		return 0, fmt.Errorf("failed to scale %s/%s: %w", ns, name, err)
+	pending := []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{}
+	errs := []error{}
+	for _, o := range objects {
+		if o.Result != cleanupv1alpha1.ResultScaled {
+			continue
+		}
+
+		mapping, err := s.lookup.LookupScaleResource(schema.FromAPIVersionAndKind(o.APIVersion, o.Kind))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		current, err := scales.Scales(o.Namespace).Get(ctx, mapping.Resource.GroupResource(), o.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("failed to get scale of %s/%s: %w", o.Namespace, o.Name, err))
+			continue
+		}
+
+		if replicas := current.Status.Replicas; replicas > 0 {
+			o.Message = fmt.Sprintf("Waiting for %d replica(s) to terminate", replicas)
+			pending = append(pending, o)
+		}
 	}
 
-	s.logger.Info("Scaled statefulset", "kind", StatefulSetKind, "namespace", ns, "name", name, "replicas", *replicas)
-	return 1, nil
+	return pending, errors.Join(errs...)
 }
@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -40,17 +41,18 @@ var _ = Describe("ScaleService", func() {
 		Expect(c.Create(ctx, statefulSet)).To(Succeed())
 
 		// Initialize services
-		lookupService = NewLookupService(ctx, c, t.Cfg)
-		scaleService = NewScaleService(ctx, c, lookupService)
+		lookupService = NewLookupService(ctx, c, t.Cfg, Options{Reader: c})
+		scaleService = NewScaleService(ctx, c, lookupService, DenyList{})
 	})
 
 	Describe("ScaleDeployment", func() {
 		It("should scale a deployment when not in dry run mode", func() {
 			replicas := testEnv.Int32Ptr(0)
-			count, err := scaleService.ScaleDeployment(ctx, false, ns.GetName(), deployment.GetName(), replicas)
+			result, err := scaleService.ScaleDeployment(ctx, false, ns.GetName(), deployment.GetName(), replicas)
 
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(1))
+			Expect(result.Result).To(Equal(cleanupv1alpha1.ResultScaled))
+			Expect(result.UID).To(Equal(deployment.GetUID()))
 
 			// Verify the deployment was scaled
 			d := &appsv1.Deployment{}
@@ -61,34 +63,100 @@ var _ = Describe("ScaleService", func() {
 
 		It("should not actually scale a deployment in dry run mode", func() {
 			replicas := testEnv.Int32Ptr(0)
-			count, err := scaleService.ScaleDeployment(ctx, true, ns.GetName(), deployment.GetName(), replicas)
+			result, err := scaleService.ScaleDeployment(ctx, true, ns.GetName(), deployment.GetName(), replicas)
 
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(1))
+			Expect(result.Result).To(Equal(cleanupv1alpha1.ResultScaled))
+			Expect(result.Message).To(Equal(DryRunMessage))
+			Expect(result.UID).To(Equal(deployment.GetUID()))
 
 			// Verify the deployment was not actually scaled
 			d := &appsv1.Deployment{}
 			err = c.Get(ctx, types.NamespacedName{Namespace: ns.GetName(), Name: deployment.GetName()}, d)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(*d.Spec.Replicas).To(Equal(int32(3))) // Should remain at original value
+			Expect(d.GetAnnotations()).NotTo(HaveKey(cleanupv1alpha1.PreviousReplicasAnnotation))
+		})
+
+		It("should report scales rejected by the API server in dry run mode", func() {
+			replicas := testEnv.Int32Ptr(-1)
+			result, err := scaleService.ScaleDeployment(ctx, true, ns.GetName(), deployment.GetName(), replicas)
+
+			Expect(err).To(HaveOccurred())
+			Expect(result.Result).To(Equal(cleanupv1alpha1.ResultFailed))
+			Expect(result.Message).To(ContainSubstring("failed to"))
 		})
 
 		It("should return error when deployment doesn't exist", func() {
 			replicas := testEnv.Int32Ptr(0)
-			count, err := scaleService.ScaleDeployment(ctx, false, ns.GetName(), "nonexistent-deployment", replicas)
+			result, err := scaleService.ScaleDeployment(ctx, false, ns.GetName(), "nonexistent-deployment", replicas)
 
 			Expect(err).To(HaveOccurred())
-			Expect(count).To(Equal(0))
+			Expect(result.Result).To(Equal(cleanupv1alpha1.ResultFailed))
+		})
+	})
+
+	Describe("RestoreObject", func() {
+		It("should record the previous replicas and restore them", func() {
+			replicas := testEnv.Int32Ptr(0)
+			result, err := scaleService.ScaleDeployment(ctx, false, ns.GetName(), deployment.GetName(), replicas)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Verify the previous replicas were recorded
+			d := &appsv1.Deployment{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(deployment), d)).To(Succeed())
+			Expect(d.GetAnnotations()).To(HaveKeyWithValue(cleanupv1alpha1.PreviousReplicasAnnotation, "3"))
+
+			// Scaling to zero again keeps the original count
+			_, err = scaleService.ScaleDeployment(ctx, false, ns.GetName(), deployment.GetName(), replicas)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(deployment), d)).To(Succeed())
+			Expect(d.GetAnnotations()).To(HaveKeyWithValue(cleanupv1alpha1.PreviousReplicasAnnotation, "3"))
+
+			restored, err := scaleService.RestoreObject(ctx, false, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restored.Result).To(Equal(cleanupv1alpha1.ResultRestored))
+
+			// Verify the deployment was restored and the annotation removed
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(deployment), d)).To(Succeed())
+			Expect(*d.Spec.Replicas).To(Equal(int32(3)))
+			Expect(d.GetAnnotations()).NotTo(HaveKey(cleanupv1alpha1.PreviousReplicasAnnotation))
+		})
+
+		It("should not restore in dry run mode", func() {
+			replicas := testEnv.Int32Ptr(0)
+			result, err := scaleService.ScaleStatefulSet(ctx, false, ns.GetName(), statefulSet.GetName(), replicas)
+			Expect(err).NotTo(HaveOccurred())
+
+			restored, err := scaleService.RestoreObject(ctx, true, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restored.Result).To(Equal(cleanupv1alpha1.ResultRestored))
+			Expect(restored.Message).To(Equal(DryRunMessage))
+
+			s := &appsv1.StatefulSet{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(statefulSet), s)).To(Succeed())
+			Expect(*s.Spec.Replicas).To(Equal(int32(0)))
+			Expect(s.GetAnnotations()).To(HaveKeyWithValue(cleanupv1alpha1.PreviousReplicasAnnotation, "3"))
+		})
+
+		It("should skip objects without a recorded replica count", func() {
+			result := NewObjectStatus(appsv1.SchemeGroupVersion.WithKind(DeploymentKind), deployment, cleanupv1alpha1.ResultScaled, nil)
+
+			restored, err := scaleService.RestoreObject(ctx, false, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restored.Result).To(Equal(cleanupv1alpha1.ResultSkipped))
+			Expect(restored.Message).To(ContainSubstring("no previous replica count"))
 		})
 	})
 
 	Describe("ScaleStatefulSet", func() {
 		It("should scale a statefulset when not in dry run mode", func() {
 			replicas := testEnv.Int32Ptr(0)
-			count, err := scaleService.ScaleStatefulSet(ctx, false, ns.GetName(), statefulSet.GetName(), replicas)
+			result, err := scaleService.ScaleStatefulSet(ctx, false, ns.GetName(), statefulSet.GetName(), replicas)
 
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(1))
+			Expect(result.Result).To(Equal(cleanupv1alpha1.ResultScaled))
+			Expect(result.UID).To(Equal(statefulSet.GetUID()))
 
 			// Verify the statefulset was scaled
 			s := &appsv1.StatefulSet{}
@@ -99,10 +167,11 @@ var _ = Describe("ScaleService", func() {
 
 		It("should not actually scale a statefulset in dry run mode", func() {
 			replicas := testEnv.Int32Ptr(0)
-			count, err := scaleService.ScaleStatefulSet(ctx, true, ns.GetName(), statefulSet.GetName(), replicas)
+			result, err := scaleService.ScaleStatefulSet(ctx, true, ns.GetName(), statefulSet.GetName(), replicas)
 
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(1))
+			Expect(result.Result).To(Equal(cleanupv1alpha1.ResultScaled))
+			Expect(result.UID).To(Equal(statefulSet.GetUID()))
 
 			// Verify the statefulset was not actually scaled
 			s := &appsv1.StatefulSet{}
@@ -113,10 +182,10 @@ var _ = Describe("ScaleService", func() {
 
 		It("should return error when statefulset doesn't exist", func() {
 			replicas := testEnv.Int32Ptr(0)
-			count, err := scaleService.ScaleStatefulSet(ctx, false, ns.GetName(), "nonexistent-statefulset", replicas)
+			result, err := scaleService.ScaleStatefulSet(ctx, false, ns.GetName(), "nonexistent-statefulset", replicas)
 
 			Expect(err).To(HaveOccurred())
-			Expect(count).To(Equal(0))
+			Expect(result.Result).To(Equal(cleanupv1alpha1.ResultFailed))
 		})
 	})
 
@@ -130,10 +199,11 @@ var _ = Describe("ScaleService", func() {
 				Action:    cleanupv1alpha1.ActionScaleToZero,
 			}
 
-			count, err := scaleService.ScaleItem(ctx, false, schema.GroupVersionKind{Kind: DeploymentKind}, item, replicas)
+			results, err := scaleService.ScaleItem(ctx, false, lookupTarget(ctx, schema.GroupVersionKind{Kind: DeploymentKind}, item), item, replicas)
 
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(1))
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Result).To(Equal(cleanupv1alpha1.ResultScaled))
 
 			// Verify the deployment was scaled
 			d := &appsv1.Deployment{}
@@ -151,10 +221,11 @@ var _ = Describe("ScaleService", func() {
 				Action:    cleanupv1alpha1.ActionScaleToZero,
 			}
 
-			count, err := scaleService.ScaleItem(ctx, false, schema.GroupVersionKind{Kind: StatefulSetKind}, item, replicas)
+			results, err := scaleService.ScaleItem(ctx, false, lookupTarget(ctx, schema.GroupVersionKind{Kind: StatefulSetKind}, item), item, replicas)
 
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(1))
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Result).To(Equal(cleanupv1alpha1.ResultScaled))
 
 			// Verify the statefulset was scaled
 			s := &appsv1.StatefulSet{}
@@ -163,6 +234,33 @@ var _ = Describe("ScaleService", func() {
 			Expect(*s.Spec.Replicas).To(Equal(int32(0)))
 		})
 
+		It("should scale any kind with a scale subresource", func() {
+			replicas := testEnv.Int32Ptr(0)
+			replicaSet := testEnv.WithRandomSuffix().ReplicaSet("test-replicaset", ns.GetName())
+			Expect(c.Create(ctx, replicaSet)).To(Succeed())
+
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      "ReplicaSet",
+				Namespace: ns.GetName(),
+				Name:      replicaSet.GetName(),
+				Action:    cleanupv1alpha1.ActionScaleToZero,
+			}
+
+			results, err := scaleService.ScaleItem(ctx, false, lookupTarget(ctx, schema.GroupVersionKind{Kind: "ReplicaSet"}, item), item, replicas)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Result).To(Equal(cleanupv1alpha1.ResultScaled))
+			Expect(results[0].APIVersion).To(Equal("apps/v1"))
+			Expect(results[0].UID).To(Equal(replicaSet.GetUID()))
+
+			// Verify the replicaset was scaled and its previous replicas recorded
+			rs := &appsv1.ReplicaSet{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(replicaSet), rs)).To(Succeed())
+			Expect(*rs.Spec.Replicas).To(Equal(int32(0)))
+			Expect(rs.GetAnnotations()).To(HaveKeyWithValue(cleanupv1alpha1.PreviousReplicasAnnotation, "3"))
+		})
+
 		It("should not support scaling for unsupported kinds", func() {
 			replicas := testEnv.Int32Ptr(0)
 			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
@@ -172,10 +270,72 @@ var _ = Describe("ScaleService", func() {
 				Action:    cleanupv1alpha1.ActionScaleToZero,
 			}
 
-			_, err := scaleService.ScaleItem(ctx, false, schema.GroupVersionKind{Kind: "Service"}, item, replicas)
+			_, err := scaleService.ScaleItem(ctx, false, lookupTarget(ctx, schema.GroupVersionKind{Kind: "Service"}, item), item, replicas)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("scaling is not supported for kind Service"))
 		})
+
+		It("should scale deployments in every namespace matching the selector", func() {
+			replicas := testEnv.Int32Ptr(0)
+			t := testEnv.WithRandomSuffix()
+			ns2 := t.Namespace("scaleservice")
+			ns2.Labels = map[string]string{"teardown": t.FormatName("scaleservice")}
+			deployment2 := t.Deployment("test-deployment", ns2.GetName())
+
+			Expect(c.Create(ctx, ns2)).To(Succeed())
+			Expect(c.Create(ctx, deployment2)).To(Succeed())
+
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:              DeploymentKind,
+				Namespaces:        []string{ns.GetName()},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: ns2.Labels},
+				Action:            cleanupv1alpha1.ActionScaleToZero,
+			}
+
+			results, err := scaleService.ScaleItem(ctx, false, lookupTarget(ctx, appsv1.SchemeGroupVersion.WithKind(DeploymentKind), item), item, replicas)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+			Expect([]string{results[0].Namespace, results[1].Namespace}).To(ConsistOf(ns.GetName(), ns2.GetName()))
+
+			// Verify the deployments were scaled in both namespaces
+			for _, key := range []types.NamespacedName{
+				{Namespace: ns.GetName(), Name: deployment.GetName()},
+				{Namespace: ns2.GetName(), Name: deployment2.GetName()},
+			} {
+				d := &appsv1.Deployment{}
+				Expect(c.Get(ctx, key, d)).To(Succeed())
+				Expect(*d.Spec.Replicas).To(Equal(int32(0)))
+			}
+		})
+
+		It("should skip deployments matching the exclude label selector", func() {
+			replicas := testEnv.Int32Ptr(0)
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+			deployment.SetLabels(map[string]string{"keep": "true"})
+			Expect(c.Update(ctx, deployment)).To(Succeed())
+
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      DeploymentKind,
+				Namespace: ns.GetName(),
+				Action:    cleanupv1alpha1.ActionScaleToZero,
+				Exclude: &cleanupv1alpha1.PreClusterDestroyCleanupExclude{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"keep": "true"}},
+				},
+			}
+
+			results, err := scaleService.ScaleItem(ctx, false, lookupTarget(ctx, appsv1.SchemeGroupVersion.WithKind(DeploymentKind), item), item, replicas)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Result).To(Equal(cleanupv1alpha1.ResultSkipped))
+			Expect(results[0].Message).To(ContainSubstring("exclude selector"))
+
+			// Verify the deployment was not scaled
+			d := &appsv1.Deployment{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(deployment), d)).To(Succeed())
+			Expect(*d.Spec.Replicas).NotTo(Equal(int32(0)))
+		})
 	})
 })
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+const (
+	// ChunkSize is the most data stored in a single ConfigMap by a StoreService, well below the 1 MiB limit of a ConfigMap.
+	ChunkSize = 768 * 1024
+
+	// ChunkKey is the key of the gzipped data in each ConfigMap of a StoreService.
+	ChunkKey = "data.json.gz"
+
+	// MaxStatusObjects is the most objects, or approved UIDs, listed in the status of an item before it is truncated.
+	MaxStatusObjects = 100
+
+	// MaxStatusMessage is the longest message of an item kept in its status.
+	MaxStatusMessage = 4096
+)
+
+// StoreService stores data too large for the status of a PreClusterDestroyCleanup, or for a single ConfigMap,
+// as gzipped JSON split into ConfigMaps owned by the PreClusterDestroyCleanup, named after a key, see ChunkName.
+type StoreService struct {
+	client client.Client
+	logger logr.Logger
+}
+
+// NewStoreService creates a new StoreService instance.
+// If opts.Reader is set, the ConfigMaps are read with it rather than with the cache of the client,
+// which would hold every ConfigMap of the cluster in memory.
+func NewStoreService(ctx context.Context, client client.Client, opts Options) *StoreService {
+	if opts.Reader != nil {
+		client = &uncachedClient{Client: client, reader: opts.Reader}
+	}
+	return &StoreService{
+		client: client,
+		logger: log.FromContext(ctx),
+	}
+}
+
+// ChunkName returns the name of the ConfigMap holding the chunk n, counting from 0, of the data stored under key.
+func ChunkName(key string, n int) string {
+	return key + "-" + strconv.Itoa(n)
+}
+
+// Save stores data under key, compressed with gzip and split into chunks of at most ChunkSize, one ConfigMap each,
+// next to the PreClusterDestroyCleanup that owns them. The first chunk records the number of chunks in the
+// ChunksAnnotation, and the chunks of a larger value previously stored under key are deleted.
+// It returns the names of the ConfigMaps holding the data and any errors encountered while writing them.
+func (s *StoreService) Save(ctx context.Context, owner *cleanupv1alpha1.PreClusterDestroyCleanup, key string, data []byte) ([]string, error) {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress %s: %w", key, err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress %s: %w", key, err)
+	}
+
+	compressed := buf.Bytes()
+	chunks := [][]byte{}
+	for len(compressed) > ChunkSize {
+		chunks = append(chunks, compressed[:ChunkSize])
+		compressed = compressed[ChunkSize:]
+	}
+	chunks = append(chunks, compressed)
+
+	names := make([]string, 0, len(chunks))
+	for n, chunk := range chunks {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: owner.GetNamespace(), Name: ChunkName(key, n)}}
+		_, err := controllerutil.CreateOrUpdate(ctx, s.client, cm, func() error {
+			cm.Data = nil
+			cm.BinaryData = map[string][]byte{ChunkKey: chunk}
+			if n == 0 {
+				metav1.SetMetaDataAnnotation(&cm.ObjectMeta, cleanupv1alpha1.ChunksAnnotation, strconv.Itoa(len(chunks)))
+			}
+			return controllerutil.SetControllerReference(owner, cm, s.client.Scheme())
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to store %s/%s: %w", cm.GetNamespace(), cm.GetName(), err)
+		}
+		names = append(names, cm.GetName())
+	}
+
+	if err := s.deleteChunks(ctx, owner.GetNamespace(), key, len(chunks)); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Stored data", "namespace", owner.GetNamespace(), "key", key, "size", len(data), "chunks", len(chunks))
+	return names, nil
+}
+
+// Load returns the data stored under key in a namespace, or nil if nothing is stored under it.
+func (s *StoreService) Load(ctx context.Context, namespace string, key string) ([]byte, error) {
+	first := &corev1.ConfigMap{}
+	if err := s.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ChunkName(key, 0)}, first); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load %s/%s: %w", namespace, ChunkName(key, 0), err)
+	}
+
+	count, err := strconv.Atoi(first.GetAnnotations()[cleanupv1alpha1.ChunksAnnotation])
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("invalid chunk count %q on %s/%s", first.GetAnnotations()[cleanupv1alpha1.ChunksAnnotation], namespace, first.GetName())
+	}
+
+	compressed := bytes.NewBuffer(first.BinaryData[ChunkKey])
+	for n := 1; n < count; n++ {
+		cm := &corev1.ConfigMap{}
+		if err := s.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ChunkName(key, n)}, cm); err != nil {
+			return nil, fmt.Errorf("failed to load %s/%s: %w", namespace, ChunkName(key, n), err)
+		}
+		compressed.Write(cm.BinaryData[ChunkKey])
+	}
+
+	zr, err := gzip.NewReader(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", key, err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Delete removes the data stored under key in a namespace, if any.
+func (s *StoreService) Delete(ctx context.Context, namespace string, key string) error {
+	return s.deleteChunks(ctx, namespace, key, 0)
+}
+
+// ObjectsName returns the key under which the full objects of the truncated items of a PreClusterDestroyCleanup are stored.
+func ObjectsName(obj *cleanupv1alpha1.PreClusterDestroyCleanup) string {
+	return obj.GetName() + "-objects"
+}
+
+// storedItem holds the full objects and approved UIDs of an item whose status is truncated, see SaveObjects.
+type storedItem struct {
+	Index        int                                                    `json:"index"`
+	Objects      []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus `json:"objects,omitempty"`
+	ApprovedUIDs []types.UID                                            `json:"approvedUIDs,omitempty"`
+}
+
+// SaveObjects keeps the status of a PreClusterDestroyCleanup within the size limit of an object before it is written.
+// Every item records the number of its objects by result, and its message is cut to MaxStatusMessage.
+// Items with more than MaxStatusObjects objects or approved UIDs are truncated: their objects and approved UIDs are
+// stored under ObjectsName, and their status only lists their failed and skipped objects, up to MaxStatusObjects.
+// The objects stored by an earlier call are deleted once no item is truncated any more.
+func (s *StoreService) SaveObjects(ctx context.Context, obj *cleanupv1alpha1.PreClusterDestroyCleanup) error {
+	stored := []storedItem{}
+	for i, st := range obj.Status.Items {
+		if len(st.Objects) > MaxStatusObjects || len(st.ApprovedUIDs) > MaxStatusObjects {
+			stored = append(stored, storedItem{Index: i, Objects: st.Objects, ApprovedUIDs: st.ApprovedUIDs})
+		}
+	}
+
+	key := ObjectsName(obj)
+	if len(stored) == 0 {
+		if len(obj.Status.ObjectsStored) > 0 {
+			if err := s.Delete(ctx, obj.GetNamespace(), key); err != nil {
+				return err
+			}
+		}
+		obj.Status.ObjectsStored = nil
+	} else {
+		data, err := json.Marshal(stored)
+		if err != nil {
+			return fmt.Errorf("failed to marshal objects: %w", err)
+		}
+		names, err := s.Save(ctx, obj, key, data)
+		if err != nil {
+			return err
+		}
+		obj.Status.ObjectsStored = names
+	}
+
+	for i := range obj.Status.Items {
+		st := &obj.Status.Items[i]
+		st.Results = ResultCounts(st.Objects)
+		st.Message = truncateMessage(st.Message, MaxStatusMessage)
+		st.ObjectsTruncated = len(st.Objects) > MaxStatusObjects || len(st.ApprovedUIDs) > MaxStatusObjects
+		if st.ObjectsTruncated {
+			st.Objects = notableObjects(st.Objects)
+			st.ApprovedUIDs = nil
+		}
+	}
+	return nil
+}
+
+// LoadObjects restores the objects and approved UIDs of the truncated items of a PreClusterDestroyCleanup
+// from the objects stored by SaveObjects, so that a reconcile works with the full lists.
+// It returns an error if the objects of a truncated item cannot be found.
+func (s *StoreService) LoadObjects(ctx context.Context, obj *cleanupv1alpha1.PreClusterDestroyCleanup) error {
+	if !slices.ContainsFunc(obj.Status.Items, func(st cleanupv1alpha1.PreClusterDestroyCleanupItemStatus) bool { return st.ObjectsTruncated }) {
+		return nil
+	}
+
+	key := ObjectsName(obj)
+	data, err := s.Load(ctx, obj.GetNamespace(), key)
+	if err != nil {
+		return err
+	}
+	stored := []storedItem{}
+	if data != nil {
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return fmt.Errorf("failed to unmarshal objects of %s: %w", key, err)
+		}
+	}
+
+	for _, item := range stored {
+		if item.Index < len(obj.Status.Items) && obj.Status.Items[item.Index].ObjectsTruncated {
+			st := &obj.Status.Items[item.Index]
+			st.Objects = item.Objects
+			st.ApprovedUIDs = item.ApprovedUIDs
+			st.ObjectsTruncated = false
+		}
+	}
+
+	for i, st := range obj.Status.Items {
+		if st.ObjectsTruncated {
+			return fmt.Errorf("objects of truncated item %d not found in %s/%s", i, obj.GetNamespace(), key)
+		}
+	}
+	return nil
+}
+
+// notableObjects returns the failed objects, then the skipped ones, up to MaxStatusObjects.
+func notableObjects(objects []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus) []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus {
+	notable := []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{}
+	for _, result := range []string{cleanupv1alpha1.ResultFailed, cleanupv1alpha1.ResultSkipped} {
+		for _, o := range objects {
+			if len(notable) == MaxStatusObjects {
+				return notable
+			}
+			if o.Result == result {
+				notable = append(notable, o)
+			}
+		}
+	}
+	return notable
+}
+
+// deleteChunks deletes the chunks stored under key from chunk n onwards, until a chunk is not found.
+func (s *StoreService) deleteChunks(ctx context.Context, namespace string, key string, n int) error {
+	for ; ; n++ {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: ChunkName(key, n)}}
+		if err := s.client.Delete(ctx, cm); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to delete %s/%s: %w", namespace, cm.GetName(), err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+var _ = Describe("StoreService", func() {
+	var (
+		ctx   context.Context
+		c     client.Client
+		store *StoreService
+		ns    *corev1.Namespace
+		obj   *cleanupv1alpha1.PreClusterDestroyCleanup
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = testEnv.K8sClient
+
+		t := testEnv.WithRandomSuffix()
+		ns = t.Namespace("storeservice")
+		obj = &cleanupv1alpha1.PreClusterDestroyCleanup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      t.FormatName("test-cleanup"),
+				Namespace: ns.GetName(),
+			},
+		}
+
+		Expect(c.Create(ctx, ns)).To(Succeed())
+		Expect(c.Create(ctx, obj)).To(Succeed())
+
+		store = NewStoreService(ctx, c, Options{Reader: c})
+	})
+
+	It("should return nil when nothing is stored", func() {
+		data, err := store.Load(ctx, ns.GetName(), "missing")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(BeNil())
+	})
+
+	It("should store data in ConfigMaps owned by the cleanup", func() {
+		names, err := store.Save(ctx, obj, "small", []byte(`{"hello":"world"}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).To(Equal([]string{ChunkName("small", 0)}))
+
+		cm := &corev1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: ns.GetName(), Name: names[0]}, cm)).To(Succeed())
+		Expect(metav1.IsControlledBy(cm, obj)).To(BeTrue())
+		Expect(cm.GetAnnotations()).To(HaveKeyWithValue(cleanupv1alpha1.ChunksAnnotation, "1"))
+
+		data, err := store.Load(ctx, ns.GetName(), "small")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"hello":"world"}`))
+	})
+
+	It("should split large data into chunks and delete stale chunks", func() {
+		large := make([]byte, 2*ChunkSize)
+		_, err := rand.Read(large)
+		Expect(err).NotTo(HaveOccurred())
+
+		names, err := store.Save(ctx, obj, "large", large)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).To(HaveLen(3))
+
+		data, err := store.Load(ctx, ns.GetName(), "large")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(large))
+
+		names, err = store.Save(ctx, obj, "large", []byte("{}"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).To(HaveLen(1))
+
+		cm := &corev1.ConfigMap{}
+		err = c.Get(ctx, client.ObjectKey{Namespace: ns.GetName(), Name: ChunkName("large", 1)}, cm)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		Expect(store.Delete(ctx, ns.GetName(), "large")).To(Succeed())
+		data, err = store.Load(ctx, ns.GetName(), "large")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(BeNil())
+	})
+
+	Describe("SaveObjects", func() {
+		It("should truncate the status of items with too many objects and restore them with LoadObjects", func() {
+			objects := []cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{}
+			uids := []types.UID{}
+			for i := range 150 {
+				result := cleanupv1alpha1.ResultDeleted
+				switch {
+				case i%5 == 0:
+					result = cleanupv1alpha1.ResultFailed
+				case i%5 == 1:
+					result = cleanupv1alpha1.ResultSkipped
+				}
+				objects = append(objects, cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus{
+					APIVersion: "v1", Kind: "Pod", Namespace: "apps", Name: fmt.Sprintf("pod-%d", i), Result: result,
+				})
+				uids = append(uids, types.UID(fmt.Sprintf("uid-%d", i)))
+			}
+			obj.Status.Items = []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{
+				{Index: 0, Kind: "Pod", Action: cleanupv1alpha1.ActionDelete, Objects: objects, ApprovedUIDs: uids},
+				{Index: 1, Kind: "Deployment", Action: cleanupv1alpha1.ActionScaleToZero, Message: strings.Repeat("x", 2*MaxStatusMessage)},
+			}
+			full := obj.Status.DeepCopy()
+
+			Expect(store.SaveObjects(ctx, obj)).To(Succeed())
+			Expect(obj.Status.ObjectsStored).To(Equal([]string{ChunkName(ObjectsName(obj), 0)}))
+			truncated := obj.Status.Items[0]
+			Expect(truncated.ObjectsTruncated).To(BeTrue())
+			Expect(truncated.Objects).To(HaveLen(60))
+			Expect(truncated.Objects[0].Result).To(Equal(cleanupv1alpha1.ResultFailed))
+			Expect(truncated.Objects[59].Result).To(Equal(cleanupv1alpha1.ResultSkipped))
+			Expect(truncated.ApprovedUIDs).To(BeEmpty())
+			Expect(truncated.Results).To(Equal(map[string]int{
+				cleanupv1alpha1.ResultDeleted: 90,
+				cleanupv1alpha1.ResultFailed:  30,
+				cleanupv1alpha1.ResultSkipped: 30,
+			}))
+			Expect(obj.Status.Items[1].ObjectsTruncated).To(BeFalse())
+			Expect(len(obj.Status.Items[1].Message)).To(Equal(MaxStatusMessage))
+
+			Expect(store.LoadObjects(ctx, obj)).To(Succeed())
+			Expect(obj.Status.Items[0].ObjectsTruncated).To(BeFalse())
+			Expect(obj.Status.Items[0].Objects).To(Equal(full.Items[0].Objects))
+			Expect(obj.Status.Items[0].ApprovedUIDs).To(Equal(full.Items[0].ApprovedUIDs))
+
+			By("deleting the stored objects once no item is truncated")
+			obj.Status.Items = obj.Status.Items[1:]
+			Expect(store.SaveObjects(ctx, obj)).To(Succeed())
+			Expect(obj.Status.ObjectsStored).To(BeEmpty())
+			data, err := store.Load(ctx, ns.GetName(), ObjectsName(obj))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(BeNil())
+		})
+
+		It("should fail to load the objects of a truncated item that were not stored", func() {
+			obj.Status.Items = []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{{Index: 0, ObjectsTruncated: true}}
+			Expect(store.LoadObjects(ctx, obj)).To(MatchError(ContainSubstring("objects of truncated item 0 not found")))
+		})
+	})
+})
@@ -1,11 +1,14 @@
 package services
 
 import (
+	"context"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
 	"github.com/MetroStar/quartz-operator/internal/testutil"
 )
 
@@ -25,3 +28,10 @@ var _ = AfterSuite(func() {
 	// Teardown the shared test environment
 	testEnv.TeardownTestEnv()
 })
+
+// lookupTarget resolves the target of an item whose kind resolved to gvk, failing the test if it cannot be resolved.
+func lookupTarget(ctx context.Context, gvk schema.GroupVersionKind, item cleanupv1alpha1.PreClusterDestroyCleanupItem) Target {
+	target, err := NewLookupService(ctx, testEnv.K8sClient, testEnv.Cfg, Options{Reader: testEnv.K8sClient}).LookupTarget(ctx, gvk, item)
+	Expect(err).NotTo(HaveOccurred())
+	return target
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+// SuspendStrategy describes declaratively how to suspend the objects of a kind.
+type SuspendStrategy struct {
+	Kind  string         `json:"kind"`  // Kind the strategy applies to, as "Kind" for any group or "Kind.group"
+	Patch map[string]any `json:"patch"` // Patch is the JSON merge patch that suspends an object
+}
+
+// DefaultSuspendStrategies are the built-in strategies for Flux, Argo CD and CronJobs.
+var DefaultSuspendStrategies = []SuspendStrategy{
+	{Kind: "Kustomization.kustomize.toolkit.fluxcd.io", Patch: map[string]any{"spec": map[string]any{"suspend": true}}},
+	{Kind: "HelmRelease.helm.toolkit.fluxcd.io", Patch: map[string]any{"spec": map[string]any{"suspend": true}}},
+	{Kind: "GitRepository.source.toolkit.fluxcd.io", Patch: map[string]any{"spec": map[string]any{"suspend": true}}},
+	{Kind: "CronJob.batch", Patch: map[string]any{"spec": map[string]any{"suspend": true}}},
+	{Kind: "Application.argoproj.io", Patch: map[string]any{"spec": map[string]any{"syncPolicy": map[string]any{"automated": nil}}}},
+}
+
+// LoadSuspendStrategies reads a list of suspend strategies from a YAML or JSON file.
+// It returns an error if the file cannot be read, or a strategy has no kind or patch.
+func LoadSuspendStrategies(path string) ([]SuspendStrategy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suspend strategies: %w", err)
+	}
+
+	strategies := []SuspendStrategy{}
+	if err := yaml.Unmarshal(data, &strategies); err != nil {
+		return nil, fmt.Errorf("failed to parse suspend strategies %s: %w", path, err)
+	}
+
+	for i, strategy := range strategies {
+		if strategy.Kind == "" || len(strategy.Patch) == 0 {
+			return nil, fmt.Errorf("suspend strategy %d in %s must specify a kind and a patch", i, path)
+		}
+	}
+	return strategies, nil
+}
+
+// SuspendService provides methods to suspend reconcilers, like Flux and Argo CD, without scaling their controllers.
+type SuspendService struct {
+	client     client.Client
+	lookup     *LookupService
+	strategies []SuspendStrategy
+	deny       DenyList
+	logger     logr.Logger
+}
+
+// NewSuspendService creates a new SuspendService instance.
+// The given strategies are added to DefaultSuspendStrategies and take precedence over them.
+func NewSuspendService(ctx context.Context, client client.Client, lookup *LookupService, deny DenyList, strategies []SuspendStrategy) *SuspendService {
+	return &SuspendService{
+		client:     client,
+		lookup:     lookup,
+		strategies: append(slices.Clone(DefaultSuspendStrategies), strategies...),
+		deny:       deny,
+		logger:     log.FromContext(ctx),
+	}
+}
+
+// LookupStrategy returns the suspend strategy for a kind. When several strategies match, the last one is used.
+// It returns an error if no strategy matches the kind.
+func (s *SuspendService) LookupStrategy(gvk schema.GroupVersionKind) (SuspendStrategy, error) {
+	for i := len(s.strategies) - 1; i >= 0; i-- {
+		if kindMatches(s.strategies[i].Kind, gvk) {
+			return s.strategies[i], nil
+		}
+	}
+	return SuspendStrategy{}, fmt.Errorf("suspend is not supported for kind %s", gvk.GroupKind())
+}
+
+// SuspendItem suspends the objects of the target of a PreClusterDestroyCleanupItem, see LookupTarget,
+// using the suspend strategy for their kind.
+// It returns the status of each object touched and any errors encountered while suspending.
+// If dryRun is true, the patches are sent with DryRunAll so the API server validates them without suspending anything.
+func (s *SuspendService) SuspendItem(ctx context.Context, dryRun bool, target Target, item cleanupv1alpha1.PreClusterDestroyCleanupItem) ([]cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+	patches := map[schema.GroupVersionKind][]byte{}
+	for _, gvk := range target.Kinds {
+		strategy, err := s.LookupStrategy(gvk)
+		if err != nil {
+			return nil, err
+		}
+
+		patch, err := json.Marshal(strategy.Patch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid suspend patch for kind %s: %w", strategy.Kind, err)
+		}
+		patches[gvk] = patch
+	}
+
+	patchOpts := []client.PatchOption{}
+	if dryRun {
+		patchOpts = append(patchOpts, client.DryRunAll)
+	}
+
+	return s.lookup.ForEachObject(ctx, target, item, s.deny, func(ctx context.Context, gvk schema.GroupVersionKind, obj *metav1.PartialObjectMetadata) (cleanupv1alpha1.PreClusterDestroyCleanupObjectStatus, error) {
+		if err := s.suspendObject(ctx, dryRun, gvk, obj, patches[gvk], patchOpts); err != nil {
+			err = fmt.Errorf("failed to suspend %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+			return NewObjectStatus(gvk, obj, cleanupv1alpha1.ResultFailed, err), err
+		}
+		return ObjectStatus(dryRun, gvk, obj, cleanupv1alpha1.ResultSuspended, nil), nil
+	})
+}
+
+// suspendObject applies the suspend patch of its kind to a single object, in a span of its own.
+func (s *SuspendService) suspendObject(ctx context.Context, dryRun bool, gvk schema.GroupVersionKind, obj *metav1.PartialObjectMetadata, patch []byte, opts []client.PatchOption) error {
+	ctx, span := StartSpan(ctx, "SuspendObject", objectAttributes(dryRun, gvk, obj.GetNamespace(), obj.GetName())...)
+	s.logger.Info("Suspending item", "kind", gvk.Kind, "namespace", obj.GetNamespace(), "name", obj.GetName(), "dryRun", dryRun)
+	err := s.client.Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch), opts...)
+	EndSpan(span, err)
+	return err
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+var _ = Describe("SuspendService", func() {
+	var (
+		ctx            context.Context
+		c              client.Client
+		suspendService *SuspendService
+		lookupService  *LookupService
+		ns             *corev1.Namespace
+		cronJob        *batchv1.CronJob
+		deployment     *appsv1.Deployment
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = testEnv.K8sClient
+
+		// Create test resources
+		t := testEnv.WithRandomSuffix()
+		ns = t.Namespace("suspendservice")
+		cronJob = t.CronJob("test-cronjob", ns.GetName())
+		deployment = t.Deployment("test-deployment", ns.GetName())
+
+		Expect(c.Create(ctx, ns)).To(Succeed())
+		Expect(c.Create(ctx, cronJob)).To(Succeed())
+		Expect(c.Create(ctx, deployment)).To(Succeed())
+
+		// Initialize services
+		lookupService = NewLookupService(ctx, c, t.Cfg, Options{Reader: c})
+		suspendService = NewSuspendService(ctx, c, lookupService, DenyList{}, nil)
+	})
+
+	Describe("LookupStrategy", func() {
+		It("should find the built-in strategies", func() {
+			for _, gvk := range []schema.GroupVersionKind{
+				{Group: "batch", Version: "v1", Kind: "CronJob"},
+				{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Kind: "Kustomization"},
+				{Group: "helm.toolkit.fluxcd.io", Version: "v2", Kind: "HelmRelease"},
+				{Group: "source.toolkit.fluxcd.io", Version: "v1", Kind: "GitRepository"},
+				{Group: "argoproj.io", Version: "v1alpha1", Kind: "Application"},
+			} {
+				_, err := suspendService.LookupStrategy(gvk)
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("should prefer the given strategies over the built-in ones", func() {
+			patch := map[string]any{"spec": map[string]any{"suspend": true, "startingDeadlineSeconds": 10}}
+			suspendService = NewSuspendService(ctx, c, lookupService, DenyList{}, []SuspendStrategy{{Kind: "CronJob", Patch: patch}})
+
+			strategy, err := suspendService.LookupStrategy(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strategy.Patch).To(Equal(patch))
+		})
+
+		It("should return error for kinds without a strategy", func() {
+			_, err := suspendService.LookupStrategy(appsv1.SchemeGroupVersion.WithKind(DeploymentKind))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("suspend is not supported"))
+		})
+	})
+
+	Describe("SuspendItem", func() {
+		It("should suspend a cronjob", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      "CronJob",
+				Namespace: ns.GetName(),
+				Action:    cleanupv1alpha1.ActionSuspend,
+			}
+
+			results, err := suspendService.SuspendItem(ctx, false, lookupTarget(ctx, batchv1.SchemeGroupVersion.WithKind("CronJob"), item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Name).To(Equal(cronJob.GetName()))
+			Expect(results[0].Result).To(Equal(cleanupv1alpha1.ResultSuspended))
+
+			// Verify the cronjob was suspended
+			cj := &batchv1.CronJob{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(cronJob), cj)).To(Succeed())
+			Expect(cj.Spec.Suspend).NotTo(BeNil())
+			Expect(*cj.Spec.Suspend).To(BeTrue())
+		})
+
+		It("should not suspend in dry run mode", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      "CronJob",
+				Namespace: ns.GetName(),
+				Name:      cronJob.GetName(),
+				Action:    cleanupv1alpha1.ActionSuspend,
+			}
+
+			results, err := suspendService.SuspendItem(ctx, true, lookupTarget(ctx, batchv1.SchemeGroupVersion.WithKind("CronJob"), item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Result).To(Equal(cleanupv1alpha1.ResultSuspended))
+			Expect(results[0].Message).To(Equal(DryRunMessage))
+
+			cj := &batchv1.CronJob{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(cronJob), cj)).To(Succeed())
+			Expect(cj.Spec.Suspend == nil || !*cj.Spec.Suspend).To(BeTrue())
+		})
+
+		It("should suspend kinds described by a custom strategy", func() {
+			suspendService = NewSuspendService(ctx, c, lookupService, DenyList{}, []SuspendStrategy{
+				{Kind: "Deployment.apps", Patch: map[string]any{"spec": map[string]any{"paused": true}}},
+			})
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      DeploymentKind,
+				Namespace: ns.GetName(),
+				Name:      deployment.GetName(),
+				Action:    cleanupv1alpha1.ActionSuspend,
+			}
+
+			results, err := suspendService.SuspendItem(ctx, false, lookupTarget(ctx, appsv1.SchemeGroupVersion.WithKind(DeploymentKind), item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Result).To(Equal(cleanupv1alpha1.ResultSuspended))
+
+			d := &appsv1.Deployment{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(deployment), d)).To(Succeed())
+			Expect(d.Spec.Paused).To(BeTrue())
+		})
+
+		It("should skip protected objects", func() {
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(cronJob), cronJob)).To(Succeed())
+			cronJob.SetAnnotations(map[string]string{cleanupv1alpha1.ProtectAnnotation: "true"})
+			Expect(c.Update(ctx, cronJob)).To(Succeed())
+
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      "CronJob",
+				Namespace: ns.GetName(),
+				Action:    cleanupv1alpha1.ActionSuspend,
+			}
+
+			results, err := suspendService.SuspendItem(ctx, false, lookupTarget(ctx, batchv1.SchemeGroupVersion.WithKind("CronJob"), item), item)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Result).To(Equal(cleanupv1alpha1.ResultSkipped))
+		})
+
+		It("should return error for kinds without a strategy", func() {
+			item := cleanupv1alpha1.PreClusterDestroyCleanupItem{
+				Kind:      DeploymentKind,
+				Namespace: ns.GetName(),
+				Action:    cleanupv1alpha1.ActionSuspend,
+			}
+
+			_, err := suspendService.SuspendItem(ctx, false, lookupTarget(ctx, appsv1.SchemeGroupVersion.WithKind(DeploymentKind), item), item)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("LoadSuspendStrategies", func() {
+		It("should load strategies from a YAML file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "strategies.yaml")
+			Expect(os.WriteFile(path, []byte(`
+- kind: ScaledObject.keda.sh
+  patch:
+    metadata:
+      annotations:
+        autoscaling.keda.sh/paused: "true"
+`), 0o600)).To(Succeed())
+
+			strategies, err := LoadSuspendStrategies(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strategies).To(HaveLen(1))
+			Expect(strategies[0].Kind).To(Equal("ScaledObject.keda.sh"))
+			Expect(strategies[0].Patch).To(HaveKey("metadata"))
+		})
+
+		It("should return an error for strategies without a patch", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "strategies.yaml")
+			Expect(os.WriteFile(path, []byte("- kind: ScaledObject.keda.sh\n"), 0o600)).To(Succeed())
+
+			_, err := LoadSuspendStrategies(path)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
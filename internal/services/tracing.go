@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TracerName is the name of the tracer that records the spans of the operator.
+const TracerName = "github.com/MetroStar/quartz-operator"
+
+const (
+	AttributeKind      = attribute.Key("quartz.kind")      // AttributeKind is the kind of the objects a span acts on
+	AttributeAction    = attribute.Key("quartz.action")    // AttributeAction is the action of the item a span performs
+	AttributeNamespace = attribute.Key("quartz.namespace") // AttributeNamespace is the namespace of the objects a span acts on
+	AttributeName      = attribute.Key("quartz.name")      // AttributeName is the name of the object a span acts on
+	AttributeDryRun    = attribute.Key("quartz.dry_run")   // AttributeDryRun is whether the requests of a span are dry run
+)
+
+// StartSpan starts a span with the global tracer provider, which does not record anything unless tracing is configured.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan ends a span, recording err and marking the span as failed if err is not nil.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// objectAttributes returns the span attributes identifying an object.
+func objectAttributes(dryRun bool, gvk schema.GroupVersionKind, ns string, name string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		AttributeKind.String(gvk.Kind),
+		AttributeNamespace.String(ns),
+		AttributeName.String(name),
+		AttributeDryRun.Bool(dryRun),
+	}
+}
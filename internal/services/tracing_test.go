@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cleanupv1alpha1 "github.com/MetroStar/quartz-operator/api/v1alpha1"
+)
+
+var _ = Describe("Tracing", func() {
+	var (
+		ctx            context.Context
+		c              client.Client
+		exporter       *tracetest.InMemoryExporter
+		cleanupService *CleanupService
+		ns             *corev1.Namespace
+	)
+
+	// spans returns the ended spans with the given name.
+	spans := func(name string) []tracetest.SpanStub {
+		found := []tracetest.SpanStub{}
+		for _, s := range exporter.GetSpans() {
+			if s.Name == name {
+				found = append(found, s)
+			}
+		}
+		return found
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = testEnv.K8sClient
+
+		exporter = tracetest.NewInMemoryExporter()
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+		DeferCleanup(func() { otel.SetTracerProvider(previous) })
+
+		t := testEnv.WithRandomSuffix()
+		ns = t.Namespace("tracing")
+		Expect(c.Create(ctx, ns)).To(Succeed())
+		Expect(c.Create(ctx, t.Pod("test-pod-1", ns.GetName()))).To(Succeed())
+		Expect(c.Create(ctx, t.Pod("test-pod-2", ns.GetName()))).To(Succeed())
+
+		cleanupService = NewCleanupService(ctx, c, t.Cfg, Options{Reader: c})
+	})
+
+	AfterEach(func() {
+		Expect(c.Delete(ctx, ns)).To(Succeed())
+	})
+
+	It("should record a span per item and per object under the span of the phases", func() {
+		items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+			{Kind: "Pod", Namespace: ns.GetName(), Action: cleanupv1alpha1.ActionDelete},
+		}
+
+		_, err := cleanupService.CleanupPhases(ctx, cleanupv1alpha1.PreClusterDestroyCleanupSpec{Resources: items}, &cleanupv1alpha1.PreClusterDestroyCleanupStatus{})
+		Expect(err).NotTo(HaveOccurred())
+
+		root := spans("CleanupPhases")
+		Expect(root).To(HaveLen(1))
+		item := spans("CleanupItem")
+		Expect(item).To(HaveLen(1))
+		Expect(item[0].Parent.SpanID()).To(Equal(root[0].SpanContext.SpanID()))
+		Expect(item[0].Attributes).To(ContainElement(AttributeKind.String("Pod")))
+
+		Expect(spans("LookupNamespaces")).To(HaveLen(1))
+		Expect(spans("ListResources")).To(HaveLen(1))
+
+		objects := spans("DeleteObject")
+		Expect(objects).To(HaveLen(2))
+		for _, o := range objects {
+			Expect(o.SpanContext.TraceID()).To(Equal(root[0].SpanContext.TraceID()))
+			Expect(o.Parent.SpanID()).To(Equal(item[0].SpanContext.SpanID()))
+			Expect(o.Attributes).To(ContainElement(AttributeNamespace.String(ns.GetName())))
+		}
+	})
+
+	It("should record a span per object written by the other actions", func() {
+		items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+			{
+				Kind:      "Pod",
+				Namespace: ns.GetName(),
+				Action:    cleanupv1alpha1.ActionPatch,
+				Patch: &cleanupv1alpha1.PreClusterDestroyCleanupPatch{
+					Data: apiextensionsv1.JSON{Raw: []byte(`{"metadata":{"labels":{"teardown":"true"}}}`)},
+				},
+			},
+		}
+
+		_, err := cleanupService.CleanupPhases(ctx, cleanupv1alpha1.PreClusterDestroyCleanupSpec{Resources: items}, &cleanupv1alpha1.PreClusterDestroyCleanupStatus{})
+		Expect(err).NotTo(HaveOccurred())
+
+		item := spans("CleanupItem")
+		Expect(item).To(HaveLen(1))
+		objects := spans("PatchObject")
+		Expect(objects).To(HaveLen(2))
+		for _, o := range objects {
+			Expect(o.Parent.SpanID()).To(Equal(item[0].SpanContext.SpanID()))
+			Expect(o.Attributes).To(ContainElement(AttributeKind.String("Pod")))
+		}
+	})
+
+	It("should mark the spans of failed items as errors", func() {
+		items := []cleanupv1alpha1.PreClusterDestroyCleanupItem{
+			{Kind: "NotAKind", Namespace: ns.GetName(), Action: cleanupv1alpha1.ActionDelete},
+		}
+
+		_, err := cleanupService.CleanupPhases(ctx, cleanupv1alpha1.PreClusterDestroyCleanupSpec{Resources: items, MaxRetries: testEnv.Int32Ptr(0)}, &cleanupv1alpha1.PreClusterDestroyCleanupStatus{})
+		Expect(err).To(HaveOccurred())
+
+		item := spans("CleanupItem")
+		Expect(item).To(HaveLen(1))
+		Expect(item[0].Status.Code).To(Equal(codes.Error))
+		Expect(item[0].Events).NotTo(BeEmpty())
+	})
+
+	Describe("EndSpan", func() {
+		It("should only mark the span as failed when there is an error", func() {
+			_, span := StartSpan(ctx, "ok")
+			EndSpan(span, nil)
+			_, span = StartSpan(ctx, "failed")
+			EndSpan(span, errors.New("boom"))
+
+			Expect(spans("ok")[0].Status.Code).To(Equal(codes.Unset))
+			Expect(spans("failed")[0].Status.Code).To(Equal(codes.Error))
+			Expect(spans("failed")[0].Status.Description).To(Equal("boom"))
+		})
+	})
+})
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -23,17 +24,38 @@ func NewUpdateService(client client.Client) *UpdateService {
 	}
 }
 
-// UpdateCondition updates the status condition of a PreClusterDestroyCleanup object.
-// It sets the condition type, status, reason, and message, and updates the object status in the cluster.
-// If the update fails, it returns an error.
-func (s *UpdateService) UpdateCondition(ctx context.Context, obj *cleanupv1alpha1.PreClusterDestroyCleanup, t string, reason string, message string) error {
+// MaxConditionMessage is the longest message of a condition, as validated by the API server.
+const MaxConditionMessage = 32768
+
+// SetCondition sets a status condition of a PreClusterDestroyCleanup object without updating it in the cluster,
+// so that several changes can be written by a single PatchStatus.
+// The condition records the generation of the object it was observed for, and its message is cut to MaxConditionMessage.
+func (s *UpdateService) SetCondition(obj *cleanupv1alpha1.PreClusterDestroyCleanup, t string, status metav1.ConditionStatus, reason string, message string) {
 	meta.SetStatusCondition(&obj.Status.Conditions, metav1.Condition{
-		Type:    t,
-		Status:  metav1.ConditionTrue,
-		Reason:  reason,
-		Message: message,
+		Type:               t,
+		Status:             status,
+		ObservedGeneration: obj.Generation,
+		Reason:             reason,
+		Message:            truncateMessage(message, MaxConditionMessage),
 	})
-	if err := s.client.Status().Update(ctx, obj); err != nil {
+}
+
+// truncateMessage cuts a message longer than limit bytes, marking it as truncated, without splitting a character.
+func truncateMessage(message string, limit int) string {
+	const suffix = "... (truncated)"
+	if len(message) <= limit {
+		return message
+	}
+	return strings.ToValidUTF8(message[:limit-len(suffix)], "") + suffix
+}
+
+// PatchStatus writes the changes made to the status of a PreClusterDestroyCleanup object since base with a single
+// merge patch. Only the fields that differ from base are sent, without a resourceVersion, so the patch never conflicts:
+// fields changed by other writers since base was read are kept, unless this patch changes them too, in which case
+// the last writer wins. Lists, like conditions and items, are written as a whole when any of their entries changed.
+// If the patch fails, it returns an error.
+func (s *UpdateService) PatchStatus(ctx context.Context, obj, base *cleanupv1alpha1.PreClusterDestroyCleanup) error {
+	if err := s.client.Status().Patch(ctx, obj, client.MergeFrom(base)); err != nil {
 		return fmt.Errorf("failed to update PreClusterDestroyCleanup status: %w", err)
 	}
 
@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
+	"unicode/utf8"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -55,11 +57,13 @@ var _ = Describe("UpdateService", func() {
 		svc = NewUpdateService(c)
 	})
 
-	Context("UpdateCondition", func() {
-		It("should successfully update the status condition", func() {
-			// Call the service method
-			err := svc.UpdateCondition(ctx, obj, "TestCondition", "TestReason", "This is a test message")
-			Expect(err).NotTo(HaveOccurred())
+	Context("SetCondition", func() {
+		It("should set the status condition to be written by PatchStatus", func() {
+			base := obj.DeepCopy()
+
+			// Call the service methods
+			svc.SetCondition(obj, "TestCondition", metav1.ConditionTrue, "TestReason", "This is a test message")
+			Expect(svc.PatchStatus(ctx, obj, base)).To(Succeed())
 
 			// Verify that the condition was set
 			condition := meta.FindStatusCondition(obj.Status.Conditions, "TestCondition")
@@ -68,9 +72,9 @@ var _ = Describe("UpdateService", func() {
 			Expect(condition.Reason).To(Equal("TestReason"))
 			Expect(condition.Message).To(Equal("This is a test message"))
 
-			// Verify that the object was updated in the fake client
+			// Verify that the object was updated in the cluster
 			updatedObj := &cleanupv1alpha1.PreClusterDestroyCleanup{}
-			err = c.Get(ctx, client.ObjectKey{Name: obj.Name, Namespace: obj.Namespace}, updatedObj)
+			err := c.Get(ctx, client.ObjectKey{Name: obj.Name, Namespace: obj.Namespace}, updatedObj)
 			Expect(err).NotTo(HaveOccurred())
 
 			updatedCondition := meta.FindStatusCondition(updatedObj.Status.Conditions, "TestCondition")
@@ -80,27 +84,33 @@ var _ = Describe("UpdateService", func() {
 			Expect(updatedCondition.Message).To(Equal("This is a test message"))
 		})
 
-		It("should handle client update errors", func() {
-			// Create a fake client that returns an error on Status().Update()
-			errorClient := &fakeErrorClient{Client: c}
-			errorSvc := NewUpdateService(errorClient)
+		It("should record false statuses and the observed generation", func() {
+			svc.SetCondition(obj, "Ready", metav1.ConditionFalse, "Reconciling", "Reconciliation started")
 
-			// Call the service method
-			err := errorSvc.UpdateCondition(ctx, obj, "TestCondition", "TestReason", "This is a test message")
+			condition := meta.FindStatusCondition(obj.Status.Conditions, "Ready")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.ObservedGeneration).To(Equal(obj.Generation))
+		})
 
-			// Should return an error
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("failed to update PreClusterDestroyCleanup status"))
+		It("should cut messages longer than the API server accepts", func() {
+			base := obj.DeepCopy()
+			svc.SetCondition(obj, "Degraded", metav1.ConditionTrue, "TestReason", strings.Repeat("é", MaxConditionMessage))
+			Expect(svc.PatchStatus(ctx, obj, base)).To(Succeed())
+
+			condition := meta.FindStatusCondition(obj.Status.Conditions, "Degraded")
+			Expect(condition).NotTo(BeNil())
+			Expect(len(condition.Message)).To(BeNumerically("<=", MaxConditionMessage))
+			Expect(utf8.ValidString(condition.Message)).To(BeTrue())
+			Expect(condition.Message).To(HaveSuffix("(truncated)"))
 		})
 
 		It("should add multiple conditions", func() {
 			// Set the first condition
-			err := svc.UpdateCondition(ctx, obj, "FirstCondition", "FirstReason", "First message")
-			Expect(err).NotTo(HaveOccurred())
+			svc.SetCondition(obj, "FirstCondition", metav1.ConditionTrue, "FirstReason", "First message")
 
 			// Set a second condition
-			err = svc.UpdateCondition(ctx, obj, "SecondCondition", "SecondReason", "Second message")
-			Expect(err).NotTo(HaveOccurred())
+			svc.SetCondition(obj, "SecondCondition", metav1.ConditionTrue, "SecondReason", "Second message")
 
 			// Verify both conditions are set
 			condition1 := meta.FindStatusCondition(obj.Status.Conditions, "FirstCondition")
@@ -114,12 +124,10 @@ var _ = Describe("UpdateService", func() {
 
 		It("should update an existing condition", func() {
 			// Set initial condition
-			err := svc.UpdateCondition(ctx, obj, "TestCondition", "InitialReason", "Initial message")
-			Expect(err).NotTo(HaveOccurred())
+			svc.SetCondition(obj, "TestCondition", metav1.ConditionTrue, "InitialReason", "Initial message")
 
 			// Update the same condition
-			err = svc.UpdateCondition(ctx, obj, "TestCondition", "UpdatedReason", "Updated message")
-			Expect(err).NotTo(HaveOccurred())
+			svc.SetCondition(obj, "TestCondition", metav1.ConditionTrue, "UpdatedReason", "Updated message")
 
 			// Verify the condition was updated
 			condition := meta.FindStatusCondition(obj.Status.Conditions, "TestCondition")
@@ -128,14 +136,84 @@ var _ = Describe("UpdateService", func() {
 			Expect(condition.Message).To(Equal("Updated message"))
 		})
 	})
+
+	Context("PatchStatus", func() {
+		It("should persist item results and the current phase", func() {
+			base := obj.DeepCopy()
+			obj.Status.Phase = "quiesce"
+			obj.Status.Items = []cleanupv1alpha1.PreClusterDestroyCleanupItemStatus{
+				{
+					Index:  0,
+					Kind:   "Deployment",
+					Action: cleanupv1alpha1.ActionDelete,
+					Phase:  "quiesce",
+					State:  cleanupv1alpha1.ItemStateWaiting,
+				},
+			}
+
+			Expect(svc.PatchStatus(ctx, obj, base)).To(Succeed())
+
+			updatedObj := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(obj), updatedObj)).To(Succeed())
+			Expect(updatedObj.Status.Phase).To(Equal("quiesce"))
+			Expect(updatedObj.Status.Items).To(HaveLen(1))
+			Expect(updatedObj.Status.Items[0].State).To(Equal(cleanupv1alpha1.ItemStateWaiting))
+		})
+
+		It("should write the changes made since base", func() {
+			base := obj.DeepCopy()
+			obj.Status.Phase = "quiesce"
+			obj.Status.PlanHash = "abc"
+			Expect(svc.PatchStatus(ctx, obj, base)).To(Succeed())
+
+			base = obj.DeepCopy()
+			obj.Status.Phase = "workloads"
+			obj.Status.PlanHash = ""
+			svc.SetCondition(obj, "Ready", metav1.ConditionTrue, "TestReason", "Test message")
+			Expect(svc.PatchStatus(ctx, obj, base)).To(Succeed())
+
+			updatedObj := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(obj), updatedObj)).To(Succeed())
+			Expect(updatedObj.Status.Phase).To(Equal("workloads"))
+			Expect(updatedObj.Status.PlanHash).To(BeEmpty())
+			Expect(meta.IsStatusConditionTrue(updatedObj.Status.Conditions, "Ready")).To(BeTrue())
+		})
+
+		It("should keep the changes of other writers to the fields it did not change", func() {
+			base := obj.DeepCopy()
+
+			// Update the object behind the back of the service
+			other := obj.DeepCopy()
+			other.Status.Phase = "other"
+			other.Status.PlanHash = "other"
+			Expect(c.Status().Update(ctx, other)).To(Succeed())
+
+			obj.Status.Phase = "quiesce"
+			Expect(svc.PatchStatus(ctx, obj, base)).To(Succeed())
+			Expect(obj.GetResourceVersion()).NotTo(Equal(base.GetResourceVersion()))
+
+			updatedObj := &cleanupv1alpha1.PreClusterDestroyCleanup{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(obj), updatedObj)).To(Succeed())
+			Expect(updatedObj.Status.Phase).To(Equal("quiesce"))
+			Expect(updatedObj.Status.PlanHash).To(Equal("other"))
+		})
+
+		It("should handle client patch errors", func() {
+			errorSvc := NewUpdateService(&fakeErrorClient{Client: c})
+
+			err := errorSvc.PatchStatus(ctx, obj, obj.DeepCopy())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to update PreClusterDestroyCleanup status"))
+		})
+	})
 })
 
-// fakeErrorClient is a mock client that returns an error on Status().Update()
+// fakeErrorClient is a mock client that returns an error on Status().Patch()
 type fakeErrorClient struct {
 	client.Client
 }
 
-// Status returns a StatusWriter that always returns an error on Update
+// Status returns a StatusWriter that always returns an error on Patch
 func (f *fakeErrorClient) Status() client.StatusWriter {
 	return &fakeErrorStatusWriter{}
 }
@@ -144,12 +222,7 @@ type fakeErrorStatusWriter struct {
 	client.StatusWriter
 }
 
-// Update always returns an error
-func (f *fakeErrorStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
-	return errors.New("forced error from fake client")
-}
-
-// Patch is not used in our tests but included to satisfy the interface
+// Patch always returns an error
 func (f *fakeErrorStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
 	return errors.New("forced error from fake client")
 }
@@ -11,10 +11,13 @@ import (
 	. "github.com/onsi/gomega"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -44,6 +47,8 @@ func SetupTestEnv() *TestEnv {
 	var err error
 	err = cleanupv1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
+	err = apiextensionsv1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
 
 	By("bootstrapping test environment")
 	testEnv := &envtest.Environment{
@@ -105,6 +110,22 @@ func getFirstFoundEnvTestBinaryDir() string {
 	return ""
 }
 
+// CachedClient returns a client that reads from an informer cache, like the client of a manager,
+// and writes to the API server. The cache runs until ctx is done.
+func (t TestEnv) CachedClient(ctx context.Context) client.Client {
+	informers, err := cache.New(t.Cfg, cache.Options{Scheme: scheme.Scheme})
+	Expect(err).NotTo(HaveOccurred())
+	go func() {
+		defer GinkgoRecover()
+		Expect(informers.Start(ctx)).To(Succeed())
+	}()
+	Expect(informers.WaitForCacheSync(ctx)).To(BeTrue())
+
+	k8sClient, err := client.New(t.Cfg, client.Options{Scheme: scheme.Scheme, Cache: &client.CacheOptions{Reader: informers}})
+	Expect(err).NotTo(HaveOccurred())
+	return k8sClient
+}
+
 func (t TestEnv) WithSuffix(suffix string) *TestEnv {
 	newEnv := t
 	newEnv.suffix = suffix
@@ -227,6 +248,110 @@ func (t TestEnv) StatefulSet(name string, ns string) *appsv1.StatefulSet {
 	}
 }
 
+func (t TestEnv) ReplicaSet(name string, ns string) *appsv1.ReplicaSet {
+	n := t.FormatName(name)
+	return &appsv1.ReplicaSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ReplicaSet",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      n,
+			Namespace: ns,
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Replicas: t.Int32Ptr(3),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": n,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": n,
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  n,
+							Image: "nginx:latest",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (t TestEnv) CronJob(name string, ns string) *batchv1.CronJob {
+	n := t.FormatName(name)
+	return &batchv1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CronJob",
+			APIVersion: "batch/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      n,
+			Namespace: ns,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "*/5 * * * *",
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							RestartPolicy: corev1.RestartPolicyNever,
+							Containers: []corev1.Container{
+								{
+									Name:  n,
+									Image: "busybox:latest",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// CustomResourceDefinition returns a namespaced CRD of kind in a group unique to the test, in category.
+// Every version is served, the last one is the storage version.
+func (t TestEnv) CustomResourceDefinition(kind string, category string, versions ...string) *apiextensionsv1.CustomResourceDefinition {
+	group := t.FormatName("test") + ".quartz.metrostar.com"
+	plural := strings.ToLower(kind) + "s"
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: plural + "." + group,
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: group,
+			Scope: apiextensionsv1.NamespaceScoped,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:     plural,
+				Singular:   strings.ToLower(kind),
+				Kind:       kind,
+				ListKind:   kind + "List",
+				Categories: []string{category},
+			},
+		},
+	}
+	preserve := true
+	for i, v := range versions {
+		crd.Spec.Versions = append(crd.Spec.Versions, apiextensionsv1.CustomResourceDefinitionVersion{
+			Name:    v,
+			Served:  true,
+			Storage: i == len(versions)-1,
+			Schema: &apiextensionsv1.CustomResourceValidation{
+				OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{Type: "object", XPreserveUnknownFields: &preserve},
+			},
+		})
+	}
+	return crd
+}
+
 func (t TestEnv) Int32Ptr(i int32) *int32 {
 	return &i
 }